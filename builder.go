@@ -0,0 +1,57 @@
+package mssql
+
+import (
+	mssqlbuilder "github.com/go-rel/mssql/builder"
+	"github.com/go-rel/sql"
+	"github.com/go-rel/sql/builder"
+)
+
+// NewBufferFactory returns buffer factory of data manipulation statements, which uses ordinal @p placeholders.
+func NewBufferFactory() builder.BufferFactory {
+	return builder.BufferFactory{AllowTableSchema: true, ArgumentPlaceholder: "@p", ArgumentOrdinal: true, BoolTrueValue: "1", BoolFalseValue: "0", Quoter: builder.Quote{IDPrefix: "[", IDSuffix: "]", IDSuffixEscapeChar: "]", ValueQuote: "'", ValueQuoteEscapeChar: "'"}}
+}
+
+// NewDDLBufferFactory returns buffer factory of data definition statements, which inlines values.
+func NewDDLBufferFactory() builder.BufferFactory {
+	return builder.BufferFactory{InlineValues: true, AllowTableSchema: true, BoolTrueValue: "1", BoolFalseValue: "0", Quoter: builder.Quote{IDPrefix: "[", IDSuffix: "]", IDSuffixEscapeChar: "]", ValueQuote: "'", ValueQuoteEscapeChar: "'"}}
+}
+
+// NewQueryBuilder returns query builder used by the adapter.
+func NewQueryBuilder() mssqlbuilder.Query {
+	return mssqlbuilder.Query{Query: builder.Query{BufferFactory: NewBufferFactory(), Filter: builder.Filter{}}}
+}
+
+// NewInsertBuilder returns insert builder used by the adapter.
+func NewInsertBuilder() mssqlbuilder.Insert {
+	return mssqlbuilder.Insert{BufferFactory: NewBufferFactory()}
+}
+
+// NewInsertAllBuilder returns insert all builder used by the adapter.
+func NewInsertAllBuilder() mssqlbuilder.InsertAll {
+	return mssqlbuilder.InsertAll{BufferFactory: NewBufferFactory()}
+}
+
+// NewUpdateBuilder returns update builder used by the adapter.
+func NewUpdateBuilder() mssqlbuilder.Update {
+	return mssqlbuilder.Update{BufferFactory: NewBufferFactory(), Query: NewQueryBuilder(), Filter: builder.Filter{}}
+}
+
+// NewDeleteBuilder returns delete builder used by the adapter.
+func NewDeleteBuilder() mssqlbuilder.Delete {
+	return mssqlbuilder.Delete{BufferFactory: NewBufferFactory(), Query: NewQueryBuilder(), Filter: builder.Filter{}}
+}
+
+// NewTableBuilder returns table builder used by the adapter.
+func NewTableBuilder() mssqlbuilder.Table {
+	return mssqlbuilder.Table{BufferFactory: NewDDLBufferFactory(), ColumnMapper: columnMapper, DropKeyMapper: sql.DropKeyMapper}
+}
+
+// NewIndexBuilder returns index builder used by the adapter.
+func NewIndexBuilder() mssqlbuilder.Index {
+	return mssqlbuilder.Index{BufferFactory: NewDDLBufferFactory(), Query: builder.Query{BufferFactory: NewDDLBufferFactory(), Filter: builder.Filter{}}, Filter: builder.Filter{}}
+}
+
+// NewSchemaBuilder returns schema builder used by CreateSchema and DropSchema.
+func NewSchemaBuilder() mssqlbuilder.Schema {
+	return mssqlbuilder.Schema{BufferFactory: NewDDLBufferFactory()}
+}
@@ -0,0 +1,37 @@
+package builder
+
+import (
+	"github.com/go-rel/rel"
+	"github.com/go-rel/sql/builder"
+)
+
+// Delete builder.
+type Delete struct {
+	BufferFactory builder.BufferFactory
+	Query         builder.QueryWriter
+	Filter        builder.Filter
+}
+
+// Build SQL query and its arguments.
+func (ds Delete) Build(table string, filter rel.FilterQuery) (string, []interface{}) {
+	return ds.BuildOutput(table, filter, nil)
+}
+
+// BuildOutput builds SQL query and its arguments, with OUTPUT clause that returns deleted fields.
+func (ds Delete) BuildOutput(table string, filter rel.FilterQuery, output []string) (string, []interface{}) {
+	buffer := ds.BufferFactory.Create()
+
+	buffer.WriteString("DELETE FROM ")
+	buffer.WriteTable(table)
+
+	writeOutput(&buffer, "DELETED", output)
+
+	if !filter.None() {
+		buffer.WriteString(" WHERE ")
+		ds.Filter.Write(&buffer, table, filter, ds.Query)
+	}
+
+	buffer.WriteString(";")
+
+	return buffer.String(), buffer.Arguments()
+}
@@ -1,6 +1,8 @@
 package builder
 
 import (
+	"strings"
+
 	"github.com/go-rel/rel"
 	"github.com/go-rel/sql/builder"
 )
@@ -10,6 +12,8 @@ type Index struct {
 	BufferFactory builder.BufferFactory
 	Query         builder.QueryWriter
 	Filter        builder.Filter
+	// NullsDistinct filters out NULL values of every unique index, see IndexOptions.NullsDistinct.
+	NullsDistinct bool
 }
 
 // Build sql query for index.
@@ -23,26 +27,58 @@ func (i Index) Build(index rel.Index) string {
 		i.WriteCreateIndex(&buffer, index)
 	case rel.SchemaDrop:
 		i.WriteDropIndex(&buffer, index)
+
+		// structured options only describe the created index.
+		if _, raw, _ := indexOptions(index.Options); raw {
+			i.WriteOptions(&buffer, index.Options)
+		}
 	}
 
-	i.WriteOptions(&buffer, index.Options)
 	buffer.WriteByte(';')
 
 	return buffer.String()
 }
 
+// Validate options of the index, so options that look structured but can't be parsed are rejected
+// instead of being written as is.
+func (i Index) Validate(index rel.Index) error {
+	_, _, err := indexOptions(index.Options)
+	return err
+}
+
 // WriteCreateIndex to buffer
 func (i Index) WriteCreateIndex(buffer *builder.Buffer, index rel.Index) {
+	// invalid structured options are reported by Validate.
+	options, raw, _ := indexOptions(index.Options)
+	if raw {
+		// unstructured options are written after the index definition as is.
+		defer i.WriteOptions(buffer, index.Options)
+	}
+
+	if index.Optional {
+		buffer.WriteString("IF NOT ")
+		i.WriteIndexExists(buffer, index)
+		buffer.WriteByte(' ')
+	}
+
+	if options.Columnstore {
+		i.WriteCreateColumnstoreIndex(buffer, index, options)
+		return
+	}
+
 	buffer.WriteString("CREATE ")
 	if index.Unique {
-		buffer.WriteString("UNIQUE NONCLUSTERED ")
+		buffer.WriteString("UNIQUE ")
 	}
-	buffer.WriteString("INDEX ")
 
-	if index.Optional {
-		buffer.WriteString("IF NOT EXISTS ")
+	switch {
+	case options.Clustered:
+		buffer.WriteString("CLUSTERED ")
+	case options.NonClustered || index.Unique:
+		buffer.WriteString("NONCLUSTERED ")
 	}
 
+	buffer.WriteString("INDEX ")
 	buffer.WriteEscape(index.Name)
 	buffer.WriteString(" ON ")
 	buffer.WriteEscape(index.Table)
@@ -52,43 +88,146 @@ func (i Index) WriteCreateIndex(buffer *builder.Buffer, index rel.Index) {
 		if i > 0 {
 			buffer.WriteString(", ")
 		}
-		buffer.WriteEscape(col)
+		writeIndexColumn(buffer, col)
 	}
 	buffer.WriteString(")")
 
-	if index.Unique {
+	if len(options.Include) > 0 {
+		buffer.WriteString(" INCLUDE (")
+		for i, col := range options.Include {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteEscape(col)
+		}
+		buffer.WriteString(")")
+	}
+
+	nullsDistinct := index.Unique && (options.NullsDistinct || i.NullsDistinct)
+	if nullsDistinct {
 		buffer.WriteString(" WHERE ")
 		for i, col := range index.Columns {
 			if i > 0 {
 				buffer.WriteString(" AND ")
 			}
-			buffer.WriteEscape(col)
+			buffer.WriteEscape(indexColumnName(col))
 			buffer.WriteString(" IS NOT NULL")
 		}
 	}
 	if !index.Filter.None() {
-		if index.Unique {
+		if nullsDistinct {
 			buffer.WriteString(" AND ")
 		} else {
 			buffer.WriteString(" WHERE ")
 		}
 		i.Filter.Write(buffer, "", index.Filter, i.Query)
 	}
+
+	i.WriteWith(buffer, options)
+}
+
+// WriteCreateColumnstoreIndex to buffer.
+//
+// Columns of clustered columnstore index are written as ORDER clause, which requires SQL Server 2022.
+func (i Index) WriteCreateColumnstoreIndex(buffer *builder.Buffer, index rel.Index, options IndexOptions) {
+	buffer.WriteString("CREATE ")
+	if options.Clustered {
+		buffer.WriteString("CLUSTERED ")
+	} else {
+		buffer.WriteString("NONCLUSTERED ")
+	}
+
+	buffer.WriteString("COLUMNSTORE INDEX ")
+	buffer.WriteEscape(index.Name)
+	buffer.WriteString(" ON ")
+	buffer.WriteEscape(index.Table)
+
+	if len(index.Columns) > 0 {
+		if options.Clustered {
+			buffer.WriteString(" ORDER")
+		}
+
+		buffer.WriteString(" (")
+		for i, col := range index.Columns {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteEscape(indexColumnName(col))
+		}
+		buffer.WriteString(")")
+	}
+
+	if !index.Filter.None() {
+		buffer.WriteString(" WHERE ")
+		i.Filter.Write(buffer, "", index.Filter, i.Query)
+	}
+
+	i.WriteWith(buffer, options)
+}
+
+// WriteWith index options and filegroup to buffer.
+func (i Index) WriteWith(buffer *builder.Buffer, options IndexOptions) {
+	if with := options.with(); len(with) > 0 {
+		buffer.WriteString(" WITH (")
+		buffer.WriteString(strings.Join(with, ", "))
+		buffer.WriteByte(')')
+	}
+
+	if options.FileGroup != "" {
+		buffer.WriteString(" ON ")
+		buffer.WriteEscape(options.FileGroup)
+	}
 }
 
 // WriteDropIndex to buffer
 func (i Index) WriteDropIndex(buffer *builder.Buffer, index rel.Index) {
-	buffer.WriteString("DROP INDEX ")
-
 	if index.Optional {
-		buffer.WriteString("IF EXISTS ")
+		buffer.WriteString("IF ")
+		i.WriteIndexExists(buffer, index)
+		buffer.WriteByte(' ')
 	}
 
+	buffer.WriteString("DROP INDEX ")
 	buffer.WriteEscape(index.Name)
 	buffer.WriteString(" ON ")
 	buffer.WriteEscape(index.Table)
 }
 
+// WriteIndexExists condition to buffer, the same way optional table uses OBJECT_ID.
+func (i Index) WriteIndexExists(buffer *builder.Buffer, index rel.Index) {
+	buffer.WriteString("EXISTS (SELECT 1 FROM sys.indexes WHERE name = ")
+	buffer.WriteString(buffer.Quoter.Value(index.Name))
+	buffer.WriteString(" AND object_id = OBJECT_ID('")
+	buffer.WriteEscape(index.Table)
+	buffer.WriteString("'))")
+}
+
+// writeIndexColumn writes index column with its optional sort direction, e.g: "created_at DESC".
+func writeIndexColumn(buffer *builder.Buffer, column string) {
+	column = strings.TrimSpace(column)
+	name := indexColumnName(column)
+	buffer.WriteEscape(name)
+
+	if direction := strings.ToUpper(strings.TrimSpace(column[len(name):])); direction != "" {
+		buffer.WriteByte(' ')
+		buffer.WriteString(direction)
+	}
+}
+
+// indexColumnName returns column name without its sort direction.
+func indexColumnName(column string) string {
+	column = strings.TrimSpace(column)
+
+	if i := strings.LastIndexByte(column, ' '); i > 0 {
+		switch strings.ToUpper(column[i+1:]) {
+		case "ASC", "DESC":
+			return strings.TrimSpace(column[:i])
+		}
+	}
+
+	return column
+}
+
 // WriteOptions sql to buffer.
 func (i Index) WriteOptions(buffer *builder.Buffer, options string) {
 	if options == "" {
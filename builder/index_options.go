@@ -0,0 +1,236 @@
+package builder
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var dataCompressions = map[string]bool{
+	"NONE":                true,
+	"ROW":                 true,
+	"PAGE":                true,
+	"COLUMNSTORE":         true,
+	"COLUMNSTORE_ARCHIVE": true,
+}
+
+var indexFlags = map[string]bool{
+	"CLUSTERED":      true,
+	"NONCLUSTERED":   true,
+	"COLUMNSTORE":    true,
+	"NULLS_DISTINCT": true,
+	"PAD_INDEX":      true,
+	"ONLINE":         true,
+	"SORT_IN_TEMPDB": true,
+}
+
+// IndexOptions of SQL Server index.
+//
+// IndexOptions is encoded into rel.Index Options using String, e.g:
+//
+//	schema.CreateIndex("orders", "orders_customer_id", []string{"customer_id", "created_at DESC"},
+//		rel.Options(builder.IndexOptions{Include: []string{"total"}, FillFactor: 80}.String()))
+//
+// Options that don't contain any option keyword nor KEY=value pair are written after the column list as is,
+// otherwise options that can't be parsed by ParseIndexOptions are reported by Index.Validate.
+type IndexOptions struct {
+	// Clustered creates CLUSTERED index.
+	Clustered bool
+	// NonClustered creates NONCLUSTERED index, which is the default for unique index.
+	NonClustered bool
+	// Columnstore creates COLUMNSTORE index, which is nonclustered unless Clustered is set (COLUMNSTORE).
+	Columnstore bool
+	// NullsDistinct allows multiple NULL values in unique index (ANSI NULL semantics),
+	// by filtering out rows where any of the index columns is NULL (NULLS_DISTINCT).
+	// Filtered index can't be referenced by foreign key nor used as MERGE target, so it's disabled by default.
+	NullsDistinct bool
+	// Include non-key columns of covering index.
+	Include []string
+	// FillFactor percentage of leaf level pages (FILLFACTOR).
+	FillFactor int
+	// PadIndex applies fill factor to intermediate level pages (PAD_INDEX).
+	PadIndex bool
+	// Online keeps the table available during index operation (ONLINE).
+	Online bool
+	// SortInTempDB stores intermediate sort results in tempdb (SORT_IN_TEMPDB).
+	SortInTempDB bool
+	// CompressionDelay in minutes before delta rowgroups of columnstore index are compressed (COMPRESSION_DELAY).
+	CompressionDelay int
+	// DataCompression of the index, either NONE, ROW, PAGE, COLUMNSTORE or COLUMNSTORE_ARCHIVE (DATA_COMPRESSION).
+	DataCompression string
+	// FileGroup where the index is created (ON <filegroup>).
+	FileGroup string
+}
+
+// ParseIndexOptions parses whitespace separated options encoded by IndexOptions.String.
+func ParseIndexOptions(options string) (IndexOptions, error) {
+	var result IndexOptions
+
+	for _, token := range strings.Fields(options) {
+		key, value, hasValue := strings.Cut(token, "=")
+
+		switch key = strings.ToUpper(key); {
+		case !hasValue && key == "CLUSTERED":
+			result.Clustered = true
+		case !hasValue && key == "NONCLUSTERED":
+			result.NonClustered = true
+		case !hasValue && key == "COLUMNSTORE":
+			result.Columnstore = true
+		case !hasValue && key == "NULLS_DISTINCT":
+			result.NullsDistinct = true
+		case !hasValue && key == "PAD_INDEX":
+			result.PadIndex = true
+		case !hasValue && key == "ONLINE":
+			result.Online = true
+		case !hasValue && key == "SORT_IN_TEMPDB":
+			result.SortInTempDB = true
+		case hasValue && key == "INCLUDE" && value != "":
+			result.Include = strings.Split(value, ",")
+		case hasValue && key == "FILLFACTOR":
+			fillFactor, err := strconv.Atoi(value)
+			if err != nil || fillFactor < 1 || fillFactor > 100 {
+				return IndexOptions{}, errors.New("mssql: invalid index fill factor: " + value)
+			}
+			result.FillFactor = fillFactor
+		case hasValue && key == "COMPRESSION_DELAY":
+			compressionDelay, err := strconv.Atoi(value)
+			if err != nil || compressionDelay < 0 {
+				return IndexOptions{}, errors.New("mssql: invalid index compression delay: " + value)
+			}
+			result.CompressionDelay = compressionDelay
+		case hasValue && key == "DATA_COMPRESSION" && dataCompressions[strings.ToUpper(value)]:
+			result.DataCompression = strings.ToUpper(value)
+		case hasValue && key == "FILEGROUP" && value != "":
+			result.FileGroup = value
+		default:
+			return IndexOptions{}, errors.New("mssql: unknown index option: " + token)
+		}
+	}
+
+	if result.Clustered && result.NonClustered {
+		return IndexOptions{}, errors.New("mssql: index can't be both CLUSTERED and NONCLUSTERED")
+	}
+
+	return result, nil
+}
+
+// indexOptions parses options of the index, raw is true when options aren't encoded by IndexOptions.String.
+func indexOptions(options string) (IndexOptions, bool, error) {
+	result, err := ParseIndexOptions(options)
+	if err != nil && !hasIndexOption(options) {
+		return IndexOptions{}, true, nil
+	}
+
+	return result, false, err
+}
+
+// hasIndexOption returns true when any of whitespace separated token is option keyword or KEY=value pair.
+func hasIndexOption(options string) bool {
+	for _, token := range strings.Fields(options) {
+		key, _, hasValue := strings.Cut(token, "=")
+		key = strings.ToUpper(key)
+
+		if indexFlags[key] || (hasValue && isOptionKey(key)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOptionKey returns true when key only consists of letters and underscores.
+func isOptionKey(key string) bool {
+	for _, r := range key {
+		if (r < 'A' || r > 'Z') && r != '_' {
+			return false
+		}
+	}
+
+	return key != ""
+}
+
+// String encodes options to be used as rel.Index Options.
+func (io IndexOptions) String() string {
+	var tokens []string
+
+	if io.Clustered {
+		tokens = append(tokens, "CLUSTERED")
+	}
+
+	if io.NonClustered {
+		tokens = append(tokens, "NONCLUSTERED")
+	}
+
+	if io.Columnstore {
+		tokens = append(tokens, "COLUMNSTORE")
+	}
+
+	if io.NullsDistinct {
+		tokens = append(tokens, "NULLS_DISTINCT")
+	}
+
+	if len(io.Include) > 0 {
+		tokens = append(tokens, "INCLUDE="+strings.Join(io.Include, ","))
+	}
+
+	if io.FillFactor > 0 {
+		tokens = append(tokens, "FILLFACTOR="+strconv.Itoa(io.FillFactor))
+	}
+
+	if io.PadIndex {
+		tokens = append(tokens, "PAD_INDEX")
+	}
+
+	if io.Online {
+		tokens = append(tokens, "ONLINE")
+	}
+
+	if io.SortInTempDB {
+		tokens = append(tokens, "SORT_IN_TEMPDB")
+	}
+
+	if io.CompressionDelay > 0 {
+		tokens = append(tokens, "COMPRESSION_DELAY="+strconv.Itoa(io.CompressionDelay))
+	}
+
+	if io.DataCompression != "" {
+		tokens = append(tokens, "DATA_COMPRESSION="+io.DataCompression)
+	}
+
+	if io.FileGroup != "" {
+		tokens = append(tokens, "FILEGROUP="+io.FileGroup)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// with returns relational index options written inside WITH clause.
+func (io IndexOptions) with() []string {
+	var with []string
+
+	if io.PadIndex {
+		with = append(with, "PAD_INDEX = ON")
+	}
+
+	if io.FillFactor > 0 {
+		with = append(with, "FILLFACTOR = "+strconv.Itoa(io.FillFactor))
+	}
+
+	if io.SortInTempDB {
+		with = append(with, "SORT_IN_TEMPDB = ON")
+	}
+
+	if io.Online {
+		with = append(with, "ONLINE = ON")
+	}
+
+	if io.CompressionDelay > 0 {
+		with = append(with, "COMPRESSION_DELAY = "+strconv.Itoa(io.CompressionDelay))
+	}
+
+	if io.DataCompression != "" {
+		with = append(with, "DATA_COMPRESSION = "+io.DataCompression)
+	}
+
+	return with
+}
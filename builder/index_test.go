@@ -0,0 +1,277 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/mssql/builder"
+	"github.com/go-rel/rel"
+	"github.com/go-rel/rel/where"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_Build(t *testing.T) {
+	indexBuilder := mssql.NewIndexBuilder()
+
+	tests := []struct {
+		result string
+		index  rel.Index
+	}{
+		{
+			result: "CREATE INDEX [idx_name] ON [users] ([name]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}},
+		},
+		{
+			result: "CREATE INDEX [idx_name_age] ON [users] ([name], [age]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name_age", Columns: []string{"name", "age"}},
+		},
+		{
+			result: "CREATE INDEX [idx_name] ON [users] ([name]) WHERE ([deleted]=0 AND [name] IS NOT NULL);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}, Filter: where.Eq("deleted", false).AndNotNil("name")},
+		},
+		{
+			result: "CREATE INDEX [idx_name] ON [users] ([name]) WITH (FILLFACTOR = 80);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}, Options: "WITH (FILLFACTOR = 80)"},
+		},
+		{
+			result: "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'idx_name' AND object_id = OBJECT_ID('[users]')) CREATE INDEX [idx_name] ON [users] ([name]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}, Optional: true},
+		},
+		{
+			// unique index is not filtered by default, so it can be referenced by foreign key and used as MERGE target.
+			result: "CREATE UNIQUE NONCLUSTERED INDEX [idx_email] ON [users] ([email]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_email", Unique: true, Columns: []string{"email"}},
+		},
+		{
+			result: "CREATE UNIQUE NONCLUSTERED INDEX [idx_email] ON [users] ([email]) WHERE [deleted]=0;",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_email", Unique: true, Columns: []string{"email"}, Filter: where.Eq("deleted", false)},
+		},
+		{
+			// NULLS_DISTINCT allows multiple NULL values by filtering them out of the unique index.
+			result: "CREATE UNIQUE NONCLUSTERED INDEX [idx_email_phone] ON [users] ([email], [phone] DESC) WHERE [email] IS NOT NULL AND [phone] IS NOT NULL;",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_email_phone", Unique: true, Columns: []string{"email", "phone DESC"}, Options: "NULLS_DISTINCT"},
+		},
+		{
+			result: "CREATE UNIQUE NONCLUSTERED INDEX [idx_email] ON [users] ([email]) WHERE [email] IS NOT NULL AND [deleted]=0;",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_email", Unique: true, Columns: []string{"email"}, Filter: where.Eq("deleted", false), Options: builder.IndexOptions{NullsDistinct: true}.String()},
+		},
+		{
+			// NULLS_DISTINCT only applies to unique index.
+			result: "CREATE INDEX [idx_email] ON [users] ([email]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_email", Columns: []string{"email"}, Options: "NULLS_DISTINCT"},
+		},
+		{
+			result: "CREATE CLUSTERED INDEX [idx_created_at] ON [orders] ([created_at] DESC, [id]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "orders", Name: "idx_created_at", Columns: []string{"created_at DESC", "id"}, Options: "CLUSTERED"},
+		},
+		{
+			result: "CREATE NONCLUSTERED INDEX [idx_customer_id] ON [orders] ([customer_id] ASC) INCLUDE ([total], [status]) WHERE [deleted]=0 WITH (PAD_INDEX = ON, FILLFACTOR = 80, SORT_IN_TEMPDB = ON, ONLINE = ON, DATA_COMPRESSION = PAGE) ON [indexes];",
+			index: rel.Index{
+				Op:      rel.SchemaCreate,
+				Table:   "orders",
+				Name:    "idx_customer_id",
+				Columns: []string{"customer_id asc"},
+				Filter:  where.Eq("deleted", false),
+				Options: builder.IndexOptions{NonClustered: true, Include: []string{"total", "status"}, FillFactor: 80, PadIndex: true, Online: true, SortInTempDB: true, DataCompression: "PAGE", FileGroup: "indexes"}.String(),
+			},
+		},
+		{
+			result: "CREATE UNIQUE CLUSTERED INDEX [idx_code] ON [orders] ([code] DESC) WITH (ONLINE = ON);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "orders", Name: "idx_code", Unique: true, Columns: []string{"code DESC"}, Options: "clustered online"},
+		},
+		{
+			result: "CREATE CLUSTERED COLUMNSTORE INDEX [cci_sales] ON [sales];",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "sales", Name: "cci_sales", Options: "CLUSTERED COLUMNSTORE"},
+		},
+		{
+			result: "CREATE CLUSTERED COLUMNSTORE INDEX [cci_sales] ON [sales] ORDER ([sold_at]) WITH (COMPRESSION_DELAY = 10, DATA_COMPRESSION = COLUMNSTORE_ARCHIVE) ON [archive];",
+			index: rel.Index{
+				Op:      rel.SchemaCreate,
+				Table:   "sales",
+				Name:    "cci_sales",
+				Columns: []string{"sold_at"},
+				Options: builder.IndexOptions{Clustered: true, Columnstore: true, CompressionDelay: 10, DataCompression: "COLUMNSTORE_ARCHIVE", FileGroup: "archive"}.String(),
+			},
+		},
+		{
+			result: "CREATE NONCLUSTERED COLUMNSTORE INDEX [ncci_orders] ON [orders] ([customer_id], [total]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "orders", Name: "ncci_orders", Columns: []string{"customer_id", "total DESC"}, Options: "COLUMNSTORE"},
+		},
+		{
+			result: "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'ncci_orders' AND object_id = OBJECT_ID('[orders]')) CREATE NONCLUSTERED COLUMNSTORE INDEX [ncci_orders] ON [orders] ([customer_id], [total]) WHERE [status]='closed' WITH (ONLINE = ON, COMPRESSION_DELAY = 60);",
+			index: rel.Index{
+				Op:       rel.SchemaCreate,
+				Table:    "orders",
+				Name:     "ncci_orders",
+				Columns:  []string{"customer_id", "total"},
+				Filter:   where.Eq("status", "closed"),
+				Optional: true,
+				Options:  "NONCLUSTERED COLUMNSTORE ONLINE COMPRESSION_DELAY=60",
+			},
+		},
+		{
+			result: "DROP INDEX [cci_sales] ON [sales];",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "sales", Name: "cci_sales", Options: "CLUSTERED COLUMNSTORE"},
+		},
+		{
+			result: "IF EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'ncci_orders' AND object_id = OBJECT_ID('[orders]')) DROP INDEX [ncci_orders] ON [orders];",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "orders", Name: "ncci_orders", Options: "COLUMNSTORE", Optional: true},
+		},
+		{
+			result: "DROP INDEX [idx_name] ON [users] WITH (ONLINE = ON);",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "users", Name: "idx_name", Options: "WITH (ONLINE = ON)"},
+		},
+		{
+			result: "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'idx_status' AND object_id = OBJECT_ID('[sales].[orders]')) CREATE INDEX [idx_status] ON [sales].[orders] ([status]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "sales.orders", Name: "idx_status", Columns: []string{"status"}, Optional: true},
+		},
+		{
+			result: "IF EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'idx_status' AND object_id = OBJECT_ID('[sales].[orders]')) DROP INDEX [idx_status] ON [sales].[orders];",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "sales.orders", Name: "idx_status", Optional: true},
+		},
+		{
+			result: "DROP INDEX [idx_name] ON [users];",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "users", Name: "idx_name"},
+		},
+		{
+			result: "CREATE INDEX [idx_created_at] ON [orders] ([created_at] DESC, [id]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "orders", Name: "idx_created_at", Columns: []string{" created_at desc", "  id "}},
+		},
+		{
+			// invalid structured options are reported by Validate instead of being written as is.
+			result: "CREATE INDEX [idx_name] ON [users] ([name]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}, Options: "FILLFACTOR=200"},
+		},
+		{
+			result: "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'idx_name' AND object_id = OBJECT_ID('[users]')) CREATE UNIQUE NONCLUSTERED INDEX [idx_name] ON [users] ([name]);",
+			index:  rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Unique: true, Columns: []string{"name"}, Optional: true},
+		},
+		{
+			result: "IF EXISTS (SELECT 1 FROM sys.indexes WHERE name = 'idx_name' AND object_id = OBJECT_ID('[users]')) DROP INDEX [idx_name] ON [users];",
+			index:  rel.Index{Op: rel.SchemaDrop, Table: "users", Name: "idx_name", Optional: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			assert.Equal(t, test.result, indexBuilder.Build(test.index))
+		})
+	}
+}
+
+func TestIndex_Validate(t *testing.T) {
+	indexBuilder := mssql.NewIndexBuilder()
+
+	tests := []struct {
+		options string
+		err     string
+	}{
+		{
+			options: "",
+		},
+		{
+			options: "CLUSTERED INCLUDE=total FILLFACTOR=80",
+		},
+		{
+			options: "WITH (FILLFACTOR = 80)",
+		},
+		{
+			options: "FILLFACTOR=200",
+			err:     "mssql: invalid index fill factor: 200",
+		},
+		{
+			options: "ONLINE WITH (MAXDOP = 2)",
+			err:     "mssql: unknown index option: WITH",
+		},
+		{
+			options: "INCLUDE=",
+			err:     "mssql: unknown index option: INCLUDE=",
+		},
+		{
+			options: "CLUSTERED NONCLUSTERED",
+			err:     "mssql: index can't be both CLUSTERED and NONCLUSTERED",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.options, func(t *testing.T) {
+			err := indexBuilder.Validate(rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_name", Columns: []string{"name"}, Options: test.options})
+			if test.err == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestIndex_Build_nullsDistinct(t *testing.T) {
+	indexBuilder := mssql.NewIndexBuilder()
+	indexBuilder.NullsDistinct = true
+
+	assert.Equal(t, "CREATE UNIQUE NONCLUSTERED INDEX [idx_slug] ON [users] ([slug]) WHERE [slug] IS NOT NULL;",
+		indexBuilder.Build(rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_slug", Unique: true, Columns: []string{"slug"}}))
+	assert.Equal(t, "CREATE INDEX [idx_slug] ON [users] ([slug]);",
+		indexBuilder.Build(rel.Index{Op: rel.SchemaCreate, Table: "users", Name: "idx_slug", Columns: []string{"slug"}}))
+}
+
+func TestParseIndexOptions(t *testing.T) {
+	tests := []struct {
+		options string
+		result  builder.IndexOptions
+		err     string
+	}{
+		{
+			options: "",
+		},
+		{
+			options: "CLUSTERED INCLUDE=total,status FILLFACTOR=80 PAD_INDEX ONLINE SORT_IN_TEMPDB DATA_COMPRESSION=ROW FILEGROUP=indexes",
+			result:  builder.IndexOptions{Clustered: true, Include: []string{"total", "status"}, FillFactor: 80, PadIndex: true, Online: true, SortInTempDB: true, DataCompression: "ROW", FileGroup: "indexes"},
+		},
+		{
+			options: "nonclustered nulls_distinct data_compression=page",
+			result:  builder.IndexOptions{NonClustered: true, NullsDistinct: true, DataCompression: "PAGE"},
+		},
+		{
+			options: "CLUSTERED COLUMNSTORE COMPRESSION_DELAY=30",
+			result:  builder.IndexOptions{Clustered: true, Columnstore: true, CompressionDelay: 30},
+		},
+		{
+			options: "COMPRESSION_DELAY=-1",
+			err:     "mssql: invalid index compression delay: -1",
+		},
+		{
+			options: "WITH (FILLFACTOR = 80)",
+			err:     "mssql: unknown index option: WITH",
+		},
+		{
+			options: "FILLFACTOR=101",
+			err:     "mssql: invalid index fill factor: 101",
+		},
+		{
+			options: "DATA_COMPRESSION=ZIP",
+			err:     "mssql: unknown index option: DATA_COMPRESSION=ZIP",
+		},
+		{
+			options: "CLUSTERED NONCLUSTERED",
+			err:     "mssql: index can't be both CLUSTERED and NONCLUSTERED",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.options, func(t *testing.T) {
+			result, err := builder.ParseIndexOptions(test.options)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.result, result)
+
+			encoded, err := builder.ParseIndexOptions(result.String())
+			assert.Nil(t, err)
+			assert.Equal(t, test.result, encoded)
+		})
+	}
+}
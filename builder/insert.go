@@ -1,7 +1,7 @@
 package builder
 
 import (
-	"log"
+	"sort"
 
 	"github.com/go-rel/rel"
 	"github.com/go-rel/sql/builder"
@@ -10,6 +10,8 @@ import (
 // Insert builder.
 type Insert struct {
 	BufferFactory builder.BufferFactory
+	OnConflict    OnConflict
+	OutputInto    OutputInto
 }
 
 // Build sql query and its arguments.
@@ -17,60 +19,97 @@ func (i Insert) Build(table string, primaryField string, mutates map[string]rel.
 	var (
 		buffer            = i.BufferFactory.Create()
 		_, identityInsert = mutates[primaryField]
-		arguments         = make([]interface{}, 0, len(mutates))
+		fields            = make([]string, 0, len(mutates))
+		outputInto        = i.OutputInto.enabled(table, primaryField)
 	)
 
-	if onConflict.Keys != nil {
-		log.Println("[REL] OnConflict clause is not yet supported in MSSQL, feel free to open a PR!")
+	for field, mut := range mutates {
+		if mut.Type == rel.ChangeSetOp {
+			fields = append(fields, field)
+		}
+	}
+
+	// deterministic column order keeps the statement reusable by plan cache.
+	sort.Strings(fields)
+
+	keys := i.OnConflict.Keys(primaryField, fields, onConflict)
+
+	if outputInto {
+		writeDeclareOutput(&buffer, primaryField, len(keys) > 0)
 	}
 
 	if identityInsert {
-		buffer.WriteString("SET IDENTITY_INSERT ")
-		buffer.WriteEscape(table)
-		buffer.WriteString(" ON; ")
+		writeIdentityInsert(&buffer, table, primaryField, "ON")
+		buffer.WriteByte(' ')
+	}
+
+	if len(keys) > 0 {
+		i.OnConflict.Write(&buffer, table, primaryField, keys, fields, []map[string]rel.Mutate{mutates}, onConflict, outputInto)
+	} else {
+		i.WriteInsert(&buffer, table, primaryField, fields, mutates, outputInto)
 	}
 
+	if outputInto {
+		writeSelectOutput(&buffer, primaryField, len(keys) > 0)
+	}
+
+	if identityInsert {
+		buffer.WriteByte(' ')
+		writeIdentityInsert(&buffer, table, primaryField, "OFF")
+		buffer.WriteByte(' ')
+	}
+
+	return buffer.String(), buffer.Arguments()
+}
+
+// WriteInsert statement to buffer.
+func (i Insert) WriteInsert(buffer *builder.Buffer, table string, primaryField string, fields []string, mutates map[string]rel.Mutate, outputInto bool) {
 	buffer.WriteString("INSERT INTO ")
 	buffer.WriteEscape(table)
-	buffer.WriteString(" (")
 
-	index := 0
-	for field, mut := range mutates {
-		if mut.Type == rel.ChangeSetOp {
-			if index > 0 {
-				buffer.WriteByte(',')
-			}
+	if len(fields) == 0 {
+		writeOutputPrimary(buffer, primaryField, outputInto)
+		buffer.WriteString(" DEFAULT VALUES;")
+		return
+	}
 
-			buffer.WriteEscape(field)
-			arguments = append(arguments, mut.Value)
-			index++
+	buffer.WriteString(" (")
+
+	for index, field := range fields {
+		if index > 0 {
+			buffer.WriteByte(',')
 		}
+
+		buffer.WriteEscape(field)
 	}
 
 	buffer.WriteString(")")
 
-	if primaryField != "" {
-		buffer.WriteString(" OUTPUT [INSERTED].")
-		buffer.WriteEscape(primaryField)
-	}
+	writeOutputPrimary(buffer, primaryField, outputInto)
 
 	buffer.WriteString(" VALUES (")
 
-	for index := range arguments {
+	for index, field := range fields {
 		if index > 0 {
 			buffer.WriteByte(',')
 		}
 
-		buffer.WriteValue(arguments[index])
+		buffer.WriteValue(mutates[field].Value)
 	}
 
 	buffer.WriteString(");")
+}
 
-	if identityInsert {
-		buffer.WriteString(" SET IDENTITY_INSERT ")
-		buffer.WriteEscape(table)
-		buffer.WriteString(" OFF; ")
-	}
-
-	return buffer.String(), buffer.Arguments()
+// writeIdentityInsert toggles IDENTITY_INSERT of the table, only when primary field is an identity column,
+// so explicit primary values can also be inserted to tables keyed by UNIQUEIDENTIFIER or character codes.
+func writeIdentityInsert(buffer *builder.Buffer, table string, primaryField string, value string) {
+	buffer.WriteString("IF COLUMNPROPERTY(OBJECT_ID('")
+	buffer.WriteEscape(table)
+	buffer.WriteString("'), ")
+	buffer.WriteString(buffer.Quoter.Value(primaryField))
+	buffer.WriteString(", 'IsIdentity') = 1 SET IDENTITY_INSERT ")
+	buffer.WriteEscape(table)
+	buffer.WriteByte(' ')
+	buffer.WriteString(value)
+	buffer.WriteByte(';')
 }
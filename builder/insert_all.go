@@ -1,8 +1,6 @@
 package builder
 
 import (
-	"log"
-
 	"github.com/go-rel/rel"
 	"github.com/go-rel/sql/builder"
 )
@@ -10,20 +8,18 @@ import (
 // InsertAll builder.
 type InsertAll struct {
 	BufferFactory builder.BufferFactory
+	OnConflict    OnConflict
+	OutputInto    OutputInto
 }
 
 // Build SQL string and its arguments.
 func (ia InsertAll) Build(table string, primaryField string, fields []string, bulkMutates []map[string]rel.Mutate, onConflict rel.OnConflict) (string, []interface{}) {
 	var (
 		buffer         = ia.BufferFactory.Create()
-		mutatesCount   = len(bulkMutates)
 		identityInsert = false
+		outputInto     = ia.OutputInto.enabled(table, primaryField)
 	)
 
-	if onConflict.Keys != nil {
-		log.Println("[REL] OnConflict clause is not yet supported in MSSQL, feel free to open a PR!")
-	}
-
 	for i := range fields {
 		if primaryField == fields[i] {
 			identityInsert = true
@@ -31,12 +27,40 @@ func (ia InsertAll) Build(table string, primaryField string, fields []string, bu
 		}
 	}
 
+	keys := ia.OnConflict.Keys(primaryField, fields, onConflict)
+
+	if outputInto {
+		writeDeclareOutput(&buffer, primaryField, len(keys) > 0)
+	}
+
 	if identityInsert {
-		buffer.WriteString("SET IDENTITY_INSERT ")
-		buffer.WriteEscape(table)
-		buffer.WriteString(" ON; ")
+		writeIdentityInsert(&buffer, table, primaryField, "ON")
+		buffer.WriteByte(' ')
 	}
 
+	if len(keys) > 0 {
+		ia.OnConflict.Write(&buffer, table, primaryField, keys, fields, bulkMutates, onConflict, outputInto)
+	} else {
+		ia.WriteInsertAll(&buffer, table, primaryField, fields, bulkMutates, outputInto)
+	}
+
+	if outputInto {
+		writeSelectOutput(&buffer, primaryField, len(keys) > 0)
+	}
+
+	if identityInsert {
+		buffer.WriteByte(' ')
+		writeIdentityInsert(&buffer, table, primaryField, "OFF")
+		buffer.WriteByte(' ')
+	}
+
+	return buffer.String(), buffer.Arguments()
+}
+
+// WriteInsertAll statement to buffer.
+func (ia InsertAll) WriteInsertAll(buffer *builder.Buffer, table string, primaryField string, fields []string, bulkMutates []map[string]rel.Mutate, outputInto bool) {
+	mutatesCount := len(bulkMutates)
+
 	buffer.WriteString("INSERT INTO ")
 	buffer.WriteEscape(table)
 	buffer.WriteString(" (")
@@ -51,10 +75,7 @@ func (ia InsertAll) Build(table string, primaryField string, fields []string, bu
 
 	buffer.WriteString(")")
 
-	if primaryField != "" {
-		buffer.WriteString(" OUTPUT [INSERTED].")
-		buffer.WriteEscape(primaryField)
-	}
+	writeOutputPrimary(buffer, primaryField, outputInto)
 
 	buffer.WriteString(" VALUES ")
 
@@ -80,12 +101,4 @@ func (ia InsertAll) Build(table string, primaryField string, fields []string, bu
 	}
 
 	buffer.WriteString(";")
-
-	if identityInsert {
-		buffer.WriteString(" SET IDENTITY_INSERT ")
-		buffer.WriteEscape(table)
-		buffer.WriteString(" OFF; ")
-	}
-
-	return buffer.String(), buffer.Arguments()
 }
@@ -0,0 +1,92 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/mssql/builder"
+	"github.com/go-rel/rel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertAll_Build(t *testing.T) {
+	tests := []struct {
+		result      string
+		args        []interface{}
+		fields      []string
+		bulkMutates []map[string]rel.Mutate
+		onConflict  rel.OnConflict
+		outputInto  builder.OutputInto
+	}{
+		{
+			result: "INSERT INTO [users] ([name],[age]) OUTPUT [INSERTED].[id] VALUES (@p1,DEFAULT),(@p2,@p3);",
+			args:   []interface{}{"foo", "boo", 12},
+			fields: []string{"name", "age"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"name": rel.Set("name", "foo")},
+				{"name": rel.Set("name", "boo"), "age": rel.Set("age", 12)},
+			},
+		},
+		{
+			result: "IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] ON; INSERT INTO [users] ([id],[name]) OUTPUT [INSERTED].[id] VALUES (@p1,@p2),(@p3,@p4); IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] OFF; ",
+			args:   []interface{}{1, "foo", 2, "boo"},
+			fields: []string{"id", "name"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"id": rel.Set("id", 1), "name": rel.Set("name", "foo")},
+				{"id": rel.Set("id", 2), "name": rel.Set("name", "boo")},
+			},
+		},
+		{
+			result: "MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2),(1,@p3,@p4)) AS [source] ([__ordinal],[email],[name]) ON [target].[email]=[source].[email] WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES ([source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id];",
+			args:   []interface{}{"foo@bar.com", "foo", "boo@bar.com", "boo"},
+			fields: []string{"email", "name"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo")},
+				{"email": rel.Set("email", "boo@bar.com"), "name": rel.Set("name", "boo")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Ignore: true},
+		},
+		{
+			result: "MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2,@p3),(1,@p4,@p5,@p6)) AS [source] ([__ordinal],[tenant_id],[email],[name]) ON [target].[tenant_id]=[source].[tenant_id] AND [target].[email]=[source].[email] WHEN MATCHED THEN UPDATE SET [name]=[source].[name] WHEN NOT MATCHED THEN INSERT ([tenant_id],[email],[name]) VALUES ([source].[tenant_id],[source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id];",
+			args:   []interface{}{1, "foo@bar.com", "foo", 1, "boo@bar.com", "boo"},
+			fields: []string{"tenant_id", "email", "name"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"tenant_id": rel.Set("tenant_id", 1), "email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo")},
+				{"tenant_id": rel.Set("tenant_id", 1), "email": rel.Set("email", "boo@bar.com"), "name": rel.Set("name", "boo")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"tenant_id", "email"}, Replace: true},
+		},
+		{
+			result: "DECLARE @output TABLE ([__ordinal] INT IDENTITY(1,1), [id] SQL_VARIANT); INSERT INTO [users] ([name]) OUTPUT [INSERTED].[id] INTO @output ([id]) VALUES (@p1),(@p2); SELECT [id] FROM @output ORDER BY [__ordinal];",
+			args:   []interface{}{"foo", "boo"},
+			fields: []string{"name"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"name": rel.Set("name", "foo")},
+				{"name": rel.Set("name", "boo")},
+			},
+			outputInto: builder.OutputIntoTables("users"),
+		},
+		{
+			result: "DECLARE @output TABLE ([__action] NVARCHAR(10), [__ordinal] INT, [id] SQL_VARIANT); MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2),(1,@p3,@p4)) AS [source] ([__ordinal],[email],[name]) ON [target].[email]=[source].[email] WHEN MATCHED THEN UPDATE SET [name]=[source].[name] WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES ([source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id] INTO @output ([__action],[__ordinal],[id]); SELECT [__action],[__ordinal],[id] FROM @output ORDER BY [__ordinal];",
+			args:   []interface{}{"foo@bar.com", "foo", "boo@bar.com", "boo"},
+			fields: []string{"email", "name"},
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo")},
+				{"email": rel.Set("email", "boo@bar.com"), "name": rel.Set("name", "boo")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Replace: true},
+			outputInto: builder.OutputIntoAll,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			insertAllBuilder := mssql.NewInsertAllBuilder()
+			insertAllBuilder.OutputInto = test.outputInto
+
+			statement, args := insertAllBuilder.Build("users", "id", test.fields, test.bulkMutates, test.onConflict)
+			assert.Equal(t, test.result, statement)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
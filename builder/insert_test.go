@@ -0,0 +1,142 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/mssql/builder"
+	"github.com/go-rel/rel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsert_Build(t *testing.T) {
+	tests := []struct {
+		result     string
+		args       []interface{}
+		mutates    map[string]rel.Mutate
+		onConflict rel.OnConflict
+		outputInto builder.OutputInto
+	}{
+		{
+			result: "INSERT INTO [users] ([age],[name]) OUTPUT [INSERTED].[id] VALUES (@p1,@p2);",
+			args:   []interface{}{10, "foo"},
+			mutates: map[string]rel.Mutate{
+				"name": rel.Set("name", "foo"),
+				"age":  rel.Set("age", 10),
+			},
+		},
+		{
+			result: "INSERT INTO [users] OUTPUT [INSERTED].[id] DEFAULT VALUES;",
+			mutates: map[string]rel.Mutate{
+				"age": rel.Inc("age"),
+			},
+		},
+		{
+			result: "IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] ON; INSERT INTO [users] ([id],[name]) OUTPUT [INSERTED].[id] VALUES (@p1,@p2); IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] OFF; ",
+			args:   []interface{}{1, "foo"},
+			mutates: map[string]rel.Mutate{
+				"id":   rel.Set("id", 1),
+				"name": rel.Set("name", "foo"),
+			},
+		},
+		{
+			result: "MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2)) AS [source] ([__ordinal],[email],[name]) ON [target].[email]=[source].[email] WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES ([source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id];",
+			args:   []interface{}{"foo@bar.com", "foo"},
+			mutates: map[string]rel.Mutate{
+				"email": rel.Set("email", "foo@bar.com"),
+				"name":  rel.Set("name", "foo"),
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Ignore: true},
+		},
+		{
+			result: "MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2)) AS [source] ([__ordinal],[email],[name]) ON [target].[email]=[source].[email] WHEN MATCHED THEN UPDATE SET [name]=[source].[name] WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES ([source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id];",
+			args:   []interface{}{"foo@bar.com", "foo"},
+			mutates: map[string]rel.Mutate{
+				"email": rel.Set("email", "foo@bar.com"),
+				"name":  rel.Set("name", "foo"),
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Replace: true},
+		},
+		{
+			result: "MERGE INTO [users] WITH (HOLDLOCK) AS [target] USING (VALUES (0,@p1,@p2)) AS [source] ([__ordinal],[email],[name]) ON [target].[email]=[source].[email] WHEN MATCHED THEN UPDATE SET [count]=[target].[count]+@p3 WHEN NOT MATCHED THEN INSERT ([email],[name]) VALUES ([source].[email],[source].[name]) OUTPUT $action AS [__action],[source].[__ordinal],[INSERTED].[id];",
+			args:   []interface{}{"foo@bar.com", "foo", 1},
+			mutates: map[string]rel.Mutate{
+				"email": rel.Set("email", "foo@bar.com"),
+				"name":  rel.Set("name", "foo"),
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Fragment: "UPDATE SET [count]=[target].[count]+@p3", FragmentArgs: []interface{}{1}},
+		},
+		{
+			result: "INSERT INTO [users] ([name]) OUTPUT [INSERTED].[id] VALUES (@p1);",
+			args:   []interface{}{"foo"},
+			mutates: map[string]rel.Mutate{
+				"name": rel.Set("name", "foo"),
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Ignore: true},
+		},
+		{
+			result: "DECLARE @output TABLE ([__ordinal] INT IDENTITY(1,1), [id] SQL_VARIANT); INSERT INTO [users] ([name]) OUTPUT [INSERTED].[id] INTO @output ([id]) VALUES (@p1); SELECT [id] FROM @output ORDER BY [__ordinal];",
+			args:   []interface{}{"foo"},
+			mutates: map[string]rel.Mutate{
+				"name": rel.Set("name", "foo"),
+			},
+			outputInto: builder.OutputIntoAll,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			insertBuilder := mssql.NewInsertBuilder()
+			insertBuilder.OutputInto = test.outputInto
+
+			statement, args := insertBuilder.Build("users", "id", test.mutates, test.onConflict)
+			assert.Equal(t, test.result, statement)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestInsert_Build_schema(t *testing.T) {
+	insertBuilder := mssql.NewInsertBuilder()
+
+	statement, args := insertBuilder.Build("sales.orders", "id", map[string]rel.Mutate{"id": rel.Set("id", 1)}, rel.OnConflict{})
+	assert.Equal(t, "IF COLUMNPROPERTY(OBJECT_ID('[sales].[orders]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [sales].[orders] ON; INSERT INTO [sales].[orders] ([id]) OUTPUT [INSERTED].[id] VALUES (@p1); IF COLUMNPROPERTY(OBJECT_ID('[sales].[orders]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [sales].[orders] OFF; ", statement)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestInsert_Build_identityInsert(t *testing.T) {
+	tests := []struct {
+		result       string
+		primaryField string
+		mutates      map[string]rel.Mutate
+	}{
+		{
+			result:       "INSERT INTO [users] ([name]) OUTPUT [INSERTED].[id] VALUES (@p1);",
+			primaryField: "id",
+			mutates:      map[string]rel.Mutate{"name": rel.Set("name", "foo")},
+		},
+		{
+			result:       "IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] ON; INSERT INTO [users] ([id],[name]) OUTPUT [INSERTED].[id] VALUES (@p1,@p2); IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] OFF; ",
+			primaryField: "id",
+			mutates:      map[string]rel.Mutate{"id": rel.Set("id", 1), "name": rel.Set("name", "foo")},
+		},
+		{
+			// non identity primary is guarded by the same column property.
+			result:       "IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'code', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] ON; INSERT INTO [users] ([code]) OUTPUT [INSERTED].[code] VALUES (@p1); IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'code', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] OFF; ",
+			primaryField: "code",
+			mutates:      map[string]rel.Mutate{"code": rel.Set("code", "abc")},
+		},
+		{
+			result:       "IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'user''s id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] ON; INSERT INTO [users] ([user's id]) OUTPUT [INSERTED].[user's id] VALUES (@p1); IF COLUMNPROPERTY(OBJECT_ID('[users]'), 'user''s id', 'IsIdentity') = 1 SET IDENTITY_INSERT [users] OFF; ",
+			primaryField: "user's id",
+			mutates:      map[string]rel.Mutate{"user's id": rel.Set("user's id", 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			statement, _ := mssql.NewInsertBuilder().Build("users", test.primaryField, test.mutates, rel.OnConflict{})
+			assert.Equal(t, test.result, statement)
+		})
+	}
+}
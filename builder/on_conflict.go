@@ -0,0 +1,212 @@
+package builder
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-rel/rel"
+	"github.com/go-rel/sql/builder"
+)
+
+const (
+	mergeTarget = "target"
+	mergeSource = "source"
+)
+
+// OnConflict builder translates rel.OnConflict into MERGE statement.
+type OnConflict struct{}
+
+// Keys returns conflict keys that can be matched using given fields.
+// Primary field is used when no keys specified, empty result means merge is not required,
+// which is also the case when primary field is not inserted, since generated primary value can't conflict.
+// Explicit keys that are not inserted result in plain insert, those are rejected by Validate.
+func (oc OnConflict) Keys(primaryField string, fields []string, onConflict rel.OnConflict) []string {
+	if onConflict.Keys == nil && onConflict.Fragment == "" && !onConflict.Ignore && !onConflict.Replace {
+		return nil
+	}
+
+	keys := onConflict.Keys
+	if len(keys) == 0 && primaryField != "" {
+		keys = []string{primaryField}
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if contains(fields, key) {
+			result = append(result, key)
+		}
+	}
+
+	// conflict is only possible when every key is provided.
+	if len(result) != len(keys) {
+		return nil
+	}
+
+	return result
+}
+
+// Validate records of upsert, explicit conflict keys must be set by every record, and every record must set
+// the same fields, since MERGE can't fall back to column default for fields that are absent from a record.
+func (oc OnConflict) Validate(bulkMutates []map[string]rel.Mutate, onConflict rel.OnConflict) error {
+	if onConflict.Keys == nil && onConflict.Fragment == "" && !onConflict.Ignore && !onConflict.Replace {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, mutates := range bulkMutates {
+		for field, mut := range mutates {
+			if mut.Type == rel.ChangeSetOp {
+				counts[field]++
+			}
+		}
+	}
+
+	for _, key := range onConflict.Keys {
+		if counts[key] != len(bulkMutates) {
+			return errors.New("mssql: conflict key is not set by every record: " + key)
+		}
+	}
+
+	for field, count := range counts {
+		if count != len(bulkMutates) {
+			return errors.New("mssql: field is not set by every record of upsert: " + field)
+		}
+	}
+
+	return nil
+}
+
+// Write MERGE statement to buffer.
+func (oc OnConflict) Write(buffer *builder.Buffer, table string, primaryField string, keys []string, fields []string, bulkMutates []map[string]rel.Mutate, onConflict rel.OnConflict, outputInto bool) {
+	buffer.WriteString("MERGE INTO ")
+	buffer.WriteEscape(table)
+	buffer.WriteString(" WITH (HOLDLOCK) AS ")
+	buffer.WriteEscape(mergeTarget)
+
+	oc.WriteUsing(buffer, fields, bulkMutates)
+	oc.WriteOn(buffer, keys)
+
+	switch {
+	case onConflict.Ignore:
+	case onConflict.Replace:
+		oc.WriteReplace(buffer, primaryField, keys, fields)
+	case onConflict.Fragment != "":
+		buffer.WriteString(" WHEN MATCHED THEN ")
+		buffer.WriteString(onConflict.Fragment)
+		buffer.AddArguments(onConflict.FragmentArgs...)
+	}
+
+	oc.WriteInsert(buffer, fields)
+	writeOutputMerge(buffer, primaryField, outputInto)
+	buffer.WriteString(";")
+}
+
+// WriteUsing source values to buffer, every row starts with its ordinal to align the outputted primary values.
+func (oc OnConflict) WriteUsing(buffer *builder.Buffer, fields []string, bulkMutates []map[string]rel.Mutate) {
+	buffer.WriteString(" USING (VALUES ")
+
+	for i, mutates := range bulkMutates {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		buffer.WriteByte('(')
+		buffer.WriteString(strconv.Itoa(i))
+
+		for _, field := range fields {
+			buffer.WriteByte(',')
+
+			if mut, ok := mutates[field]; ok && mut.Type == rel.ChangeSetOp {
+				buffer.WriteValue(mut.Value)
+			} else {
+				// DEFAULT is not allowed in table value constructor of MERGE, see Validate.
+				buffer.WriteString("NULL")
+			}
+		}
+		buffer.WriteByte(')')
+	}
+
+	buffer.WriteString(") AS ")
+	buffer.WriteEscape(mergeSource)
+	buffer.WriteString(" (")
+	buffer.WriteEscape(outputOrdinal)
+
+	for _, field := range fields {
+		buffer.WriteByte(',')
+		buffer.WriteEscape(field)
+	}
+
+	buffer.WriteByte(')')
+}
+
+// WriteOn merge condition to buffer.
+func (oc OnConflict) WriteOn(buffer *builder.Buffer, keys []string) {
+	buffer.WriteString(" ON ")
+
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteString(" AND ")
+		}
+
+		buffer.WriteField(mergeTarget, key)
+		buffer.WriteByte('=')
+		buffer.WriteField(mergeSource, key)
+	}
+}
+
+// WriteReplace update clause to buffer.
+func (oc OnConflict) WriteReplace(buffer *builder.Buffer, primaryField string, keys []string, fields []string) {
+	n := 0
+
+	for _, field := range fields {
+		if field == primaryField || contains(keys, field) {
+			continue
+		}
+
+		if n == 0 {
+			buffer.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		} else {
+			buffer.WriteByte(',')
+		}
+
+		buffer.WriteEscape(field)
+		buffer.WriteByte('=')
+		buffer.WriteField(mergeSource, field)
+		n++
+	}
+}
+
+// WriteInsert clause to buffer.
+func (oc OnConflict) WriteInsert(buffer *builder.Buffer, fields []string) {
+	buffer.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		buffer.WriteEscape(field)
+	}
+
+	buffer.WriteString(") VALUES (")
+
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		buffer.WriteField(mergeSource, field)
+	}
+
+	buffer.WriteByte(')')
+}
+
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+
+	return false
+}
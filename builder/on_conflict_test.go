@@ -0,0 +1,109 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql/builder"
+	"github.com/go-rel/rel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnConflict_Keys(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     []string
+		fields     []string
+		onConflict rel.OnConflict
+	}{
+		{
+			name:   "not an upsert",
+			fields: []string{"id", "email"},
+		},
+		{
+			name:       "primary field",
+			result:     []string{"id"},
+			fields:     []string{"id", "email"},
+			onConflict: rel.OnConflict{Ignore: true},
+		},
+		{
+			name:       "generated primary field",
+			fields:     []string{"email"},
+			onConflict: rel.OnConflict{Ignore: true},
+		},
+		{
+			name:       "explicit keys",
+			result:     []string{"tenant_id", "email"},
+			fields:     []string{"tenant_id", "email", "name"},
+			onConflict: rel.OnConflict{Keys: []string{"tenant_id", "email"}, Replace: true},
+		},
+		{
+			name:       "explicit keys not inserted",
+			fields:     []string{"email", "name"},
+			onConflict: rel.OnConflict{Keys: []string{"tenant_id", "email"}, Replace: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.result, builder.OnConflict{}.Keys("id", test.fields, test.onConflict))
+		})
+	}
+}
+
+func TestOnConflict_Validate(t *testing.T) {
+	tests := []struct {
+		result      string
+		bulkMutates []map[string]rel.Mutate
+		onConflict  rel.OnConflict
+	}{
+		{
+			result: "",
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com")},
+				{"name": rel.Set("name", "boo")},
+			},
+		},
+		{
+			result: "",
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo"), "count": rel.Inc("count")},
+				{"email": rel.Set("email", "boo@bar.com"), "name": rel.Set("name", "boo")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Replace: true},
+		},
+		{
+			result: "mssql: conflict key is not set by every record: email",
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo")},
+				{"name": rel.Set("name", "boo")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Ignore: true},
+		},
+		{
+			result: "mssql: conflict key is not set by every record: tenant_id",
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"tenant_id", "email"}, Ignore: true},
+		},
+		{
+			result: "mssql: field is not set by every record of upsert: name",
+			bulkMutates: []map[string]rel.Mutate{
+				{"email": rel.Set("email", "foo@bar.com"), "name": rel.Set("name", "foo")},
+				{"email": rel.Set("email", "boo@bar.com")},
+			},
+			onConflict: rel.OnConflict{Keys: []string{"email"}, Ignore: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			err := builder.OnConflict{}.Validate(test.bulkMutates, test.onConflict)
+			if test.result == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.result)
+			}
+		})
+	}
+}
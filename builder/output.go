@@ -0,0 +1,136 @@
+package builder
+
+import (
+	"github.com/go-rel/sql/builder"
+)
+
+const (
+	// outputVariable is the table variable that holds the outputted primary values.
+	outputVariable = "@output"
+	outputOrdinal  = "__ordinal"
+	outputAction   = "__action"
+)
+
+// OutputInto decides whether the outputted primary values of a table are written into a table variable,
+// which is required when the table has enabled triggers.
+type OutputInto func(table string) bool
+
+// OutputIntoAll writes the outputted primary values of every table into a table variable.
+func OutputIntoAll(table string) bool {
+	return true
+}
+
+// OutputIntoTables writes the outputted primary values of the given tables into a table variable.
+// Unqualified table matches the table of any schema.
+func OutputIntoTables(tables ...string) OutputInto {
+	return func(table string) bool {
+		_, name := splitTable(table)
+		return contains(tables, table) || contains(tables, name)
+	}
+}
+
+func (oi OutputInto) enabled(table string, primaryField string) bool {
+	return oi != nil && primaryField != "" && oi(table)
+}
+
+// writeOutput clause of the given pseudo table to buffer.
+func writeOutput(buffer *builder.Buffer, pseudoTable string, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	buffer.WriteString(" OUTPUT ")
+
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		buffer.WriteField(pseudoTable, field)
+	}
+}
+
+// writeOutputPrimary writes OUTPUT clause of the inserted primary value to buffer.
+func writeOutputPrimary(buffer *builder.Buffer, primaryField string, into bool) {
+	if primaryField == "" {
+		return
+	}
+
+	writeOutput(buffer, "INSERTED", []string{primaryField})
+
+	if into {
+		buffer.WriteString(" INTO ")
+		buffer.WriteString(outputVariable)
+		buffer.WriteString(" (")
+		buffer.WriteEscape(primaryField)
+		buffer.WriteByte(')')
+	}
+}
+
+// writeOutputMerge writes OUTPUT clause of MERGE action, ordinal of the source row and the inserted primary value
+// to buffer, since MERGE doesn't output matched rows of ignored conflict nor guarantee the order of its output.
+func writeOutputMerge(buffer *builder.Buffer, primaryField string, into bool) {
+	if primaryField == "" {
+		return
+	}
+
+	buffer.WriteString(" OUTPUT $action AS ")
+	buffer.WriteEscape(outputAction)
+	buffer.WriteByte(',')
+	buffer.WriteField(mergeSource, outputOrdinal)
+	buffer.WriteByte(',')
+	buffer.WriteField("INSERTED", primaryField)
+
+	if into {
+		buffer.WriteString(" INTO ")
+		buffer.WriteString(outputVariable)
+		buffer.WriteString(" (")
+		buffer.WriteEscape(outputAction)
+		buffer.WriteByte(',')
+		buffer.WriteEscape(outputOrdinal)
+		buffer.WriteByte(',')
+		buffer.WriteEscape(primaryField)
+		buffer.WriteByte(')')
+	}
+}
+
+// writeDeclareOutput declares table variable of the outputted primary values.
+// The type of primary column is unknown, so its value is stored as SQL_VARIANT,
+// along with an ordinal to keep the outputted order, or the action and source ordinal of MERGE.
+func writeDeclareOutput(buffer *builder.Buffer, primaryField string, merge bool) {
+	buffer.WriteString("DECLARE ")
+	buffer.WriteString(outputVariable)
+	buffer.WriteString(" TABLE (")
+
+	if merge {
+		buffer.WriteEscape(outputAction)
+		buffer.WriteString(" NVARCHAR(10), ")
+		buffer.WriteEscape(outputOrdinal)
+		buffer.WriteString(" INT, ")
+	} else {
+		buffer.WriteEscape(outputOrdinal)
+		buffer.WriteString(" INT IDENTITY(1,1), ")
+	}
+
+	buffer.WriteEscape(primaryField)
+	buffer.WriteString(" SQL_VARIANT); ")
+}
+
+// writeSelectOutput selects the outputted primary values from table variable.
+func writeSelectOutput(buffer *builder.Buffer, primaryField string, merge bool) {
+	buffer.WriteString(" SELECT ")
+
+	if merge {
+		buffer.WriteEscape(outputAction)
+		buffer.WriteByte(',')
+		buffer.WriteEscape(outputOrdinal)
+		buffer.WriteByte(',')
+	}
+
+	buffer.WriteEscape(primaryField)
+	buffer.WriteString(" FROM ")
+	buffer.WriteString(outputVariable)
+	buffer.WriteString(" ORDER BY ")
+	buffer.WriteEscape(outputOrdinal)
+	buffer.WriteByte(';')
+}
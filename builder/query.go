@@ -2,11 +2,25 @@ package builder
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/go-rel/rel"
 	"github.com/go-rel/sql/builder"
 )
 
+// lockHints maps lock clauses of other databases to SQL Server table hints.
+var lockHints = map[string]string{
+	"FOR UPDATE":             "UPDLOCK, ROWLOCK",
+	"FOR UPDATE NOWAIT":      "UPDLOCK, ROWLOCK, NOWAIT",
+	"FOR UPDATE SKIP LOCKED": "UPDLOCK, ROWLOCK, READPAST",
+	"FOR NO KEY UPDATE":      "UPDLOCK, ROWLOCK",
+	"FOR SHARE":              "HOLDLOCK",
+	"FOR SHARE NOWAIT":       "HOLDLOCK, NOWAIT",
+	"FOR SHARE SKIP LOCKED":  "HOLDLOCK, READPAST",
+	"FOR KEY SHARE":          "HOLDLOCK",
+	"LOCK IN SHARE MODE":     "HOLDLOCK",
+}
+
 // Query builder.
 type Query struct {
 	builder.Query
@@ -31,7 +45,10 @@ func (q Query) Write(buffer *builder.Buffer, query rel.Query) {
 		return
 	}
 
-	if len(query.SortQuery) == 0 && query.OffsetQuery > 0 && query.LimitQuery > 0 {
+	// OFFSET requires ORDER BY, sort by the first selected column only to make the statement valid.
+	// the order isn't stable unless the first column is unique, pages may repeat or skip rows,
+	// paging requires explicit sort of unique columns such as primary key.
+	if len(query.SortQuery) == 0 && query.OffsetQuery > 0 {
 		query = query.Sort("^1")
 	}
 
@@ -50,12 +67,13 @@ func (q Query) WriteSelect(buffer *builder.Buffer, table string, selectQuery rel
 	buffer.WriteString("SELECT")
 
 	if selectQuery.OnlyDistinct {
-		buffer.WriteString(" DISTINCT ")
+		buffer.WriteString(" DISTINCT")
 	}
 
 	if limit > 0 && offset == 0 {
-		buffer.WriteString(" TOP ")
+		buffer.WriteString(" TOP (")
 		buffer.WriteString(strconv.Itoa(int(limit)))
+		buffer.WriteByte(')')
 	}
 
 	if len(selectQuery.Fields) > 0 {
@@ -77,6 +95,7 @@ func (q Query) WriteSelect(buffer *builder.Buffer, table string, selectQuery rel
 // WriteQuery SQL to buffer.
 func (q Query) WriteQuery(buffer *builder.Buffer, query rel.Query) {
 	q.WriteFrom(buffer, query.Table)
+	q.WriteLock(buffer, query.LockQuery)
 	q.WriteJoin(buffer, query.Table, query.JoinQuery)
 	q.WriteWhere(buffer, query.Table, query.WhereQuery)
 
@@ -87,10 +106,29 @@ func (q Query) WriteQuery(buffer *builder.Buffer, query rel.Query) {
 
 	q.WriteOrderBy(buffer, query.Table, query.SortQuery)
 	q.WriteLimitOffet(buffer, query.LimitQuery, query.OffsetQuery)
+}
 
-	if query.LockQuery != "" {
-		buffer.WriteByte(' ')
-		buffer.WriteString(string(query.LockQuery))
+// WriteLock table hints to buffer.
+//
+// Lock clauses such as FOR UPDATE are translated to its equivalent table hints,
+// any other value is treated as table hints, e.g: rel.Lock("NOLOCK") or rel.Lock("WITH (TABLOCKX)").
+func (q Query) WriteLock(buffer *builder.Buffer, lock rel.Lock) {
+	hints := strings.TrimSpace(string(lock))
+	if hints == "" {
+		return
+	}
+
+	if h, ok := lockHints[strings.ToUpper(strings.Join(strings.Fields(hints), " "))]; ok {
+		hints = h
+	}
+
+	buffer.WriteByte(' ')
+	if strings.HasPrefix(strings.ToUpper(hints), "WITH") {
+		buffer.WriteString(hints)
+	} else {
+		buffer.WriteString("WITH (")
+		buffer.WriteString(hints)
+		buffer.WriteByte(')')
 	}
 }
 
@@ -115,11 +153,20 @@ func (q Query) WriteHaving(buffer *builder.Buffer, table string, filter rel.Filt
 }
 
 // WriteLimitOffet SQL to buffer.
+//
+// Limit without offset is written as TOP by WriteSelect, offset without sort is ordered by
+// the first selected column, which doesn't guarantee stable paging.
 func (q Query) WriteLimitOffet(buffer *builder.Buffer, limit rel.Limit, offset rel.Offset) {
-	if limit > 0 && offset > 0 {
-		buffer.WriteString(" OFFSET ")
-		buffer.WriteString(strconv.Itoa(int(offset)))
-		buffer.WriteString(" ROWS FETCH NEXT ")
+	if offset <= 0 {
+		return
+	}
+
+	buffer.WriteString(" OFFSET ")
+	buffer.WriteString(strconv.Itoa(int(offset)))
+	buffer.WriteString(" ROWS")
+
+	if limit > 0 {
+		buffer.WriteString(" FETCH NEXT ")
 		buffer.WriteString(strconv.Itoa(int(limit)))
 		buffer.WriteString(" ROWS ONLY")
 	}
@@ -0,0 +1,132 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/rel"
+	"github.com/go-rel/rel/where"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_Build(t *testing.T) {
+	queryBuilder := mssql.NewQueryBuilder()
+
+	tests := []struct {
+		result string
+		args   []interface{}
+		query  rel.Query
+	}{
+		{
+			result: "SELECT * FROM [users];",
+			query:  rel.From("users"),
+		},
+		{
+			result: "SELECT [users].[id], [users].[name] FROM [users];",
+			query:  rel.Select("id", "name").From("users"),
+		},
+		{
+			result: "SELECT * FROM [users] WHERE [users].[id]=@p1;",
+			args:   []interface{}{1},
+			query:  rel.From("users").Where(where.Eq("id", 1)),
+		},
+		{
+			result: "SELECT * FROM [users] WHERE ([users].[age]>@p1 AND [users].[name] IN (@p2,@p3));",
+			args:   []interface{}{10, "a", "b"},
+			query:  rel.From("users").Where(where.Gt("age", 10), where.In("name", "a", "b")),
+		},
+		{
+			result: "SELECT * FROM [users] JOIN [addresses] ON [addresses].[user_id]=[users].[id];",
+			query:  rel.From("users").JoinOn("addresses", "addresses.user_id", "users.id"),
+		},
+		{
+			result: "SELECT [users].[gender], COUNT([users].[id]) AS [count] FROM [users] GROUP BY [users].[gender] HAVING [users].[gender]=@p1;",
+			args:   []interface{}{"male"},
+			query:  rel.Select("gender", "COUNT(id) AS count").From("users").Group("gender").Having(where.Eq("gender", "male")),
+		},
+		{
+			result: "SELECT * FROM [users] ORDER BY [users].[name] ASC, [users].[id] DESC;",
+			query:  rel.From("users").SortAsc("name").SortDesc("id"),
+		},
+		{
+			result: "SELECT TOP (10) * FROM [users];",
+			query:  rel.From("users").Limit(10),
+		},
+		{
+			result: "SELECT * FROM [users] ORDER BY 1 ASC OFFSET 20 ROWS;",
+			query:  rel.From("users").Offset(20),
+		},
+		{
+			result: "SELECT * FROM [users] ORDER BY 1 ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY;",
+			query:  rel.From("users").Limit(10).Offset(20),
+		},
+		{
+			result: "SELECT * FROM [users] ORDER BY [users].[id] DESC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY;",
+			query:  rel.From("users").Limit(10).Offset(20).SortDesc("id"),
+		},
+		{
+			result: "SELECT TOP (10) * FROM [users] ORDER BY [users].[name] ASC;",
+			query:  rel.From("users").Limit(10).SortAsc("name"),
+		},
+		{
+			result: "SELECT * FROM [users] ORDER BY [users].[name] DESC OFFSET 20 ROWS;",
+			query:  rel.From("users").Offset(20).SortDesc("name"),
+		},
+		{
+			result: "SELECT DISTINCT * FROM [users];",
+			query:  rel.From("users").Distinct(),
+		},
+		{
+			result: "SELECT DISTINCT TOP (10) [users].[name] FROM [users];",
+			query:  rel.Select("name").From("users").Distinct().Limit(10),
+		},
+		{
+			result: "SELECT DISTINCT TOP (10) [users].[name] FROM [users] ORDER BY [users].[name] ASC;",
+			query:  rel.Select("name").From("users").Distinct().Limit(10).SortAsc("name"),
+		},
+		{
+			result: "SELECT DISTINCT [users].[name] FROM [users] ORDER BY 1 ASC OFFSET 20 ROWS;",
+			query:  rel.Select("name").From("users").Distinct().Offset(20),
+		},
+		{
+			result: "SELECT DISTINCT [users].[name], [users].[age] FROM [users] ORDER BY 1 ASC OFFSET 20 ROWS;",
+			query:  rel.Select("name", "age").From("users").Distinct().Offset(20),
+		},
+		{
+			result: "SELECT DISTINCT [users].[name] FROM [users] ORDER BY 1 ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY;",
+			query:  rel.Select("name").From("users").Distinct().Limit(10).Offset(20),
+		},
+		{
+			result: "SELECT DISTINCT [users].[name] FROM [users] ORDER BY [users].[name] ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY;",
+			query:  rel.Select("name").From("users").Distinct().Limit(10).Offset(20).SortAsc("name"),
+		},
+		{
+			result: "SELECT TOP (1) * FROM [jobs] WITH (UPDLOCK, ROWLOCK, READPAST) WHERE [jobs].[status]=@p1 ORDER BY [jobs].[id] ASC;",
+			args:   []interface{}{"pending"},
+			query:  rel.From("jobs").Where(where.Eq("status", "pending")).SortAsc("id").Limit(1).Lock("FOR UPDATE SKIP LOCKED"),
+		},
+		{
+			result: "SELECT * FROM [users] WHERE [users].[id] IN (SELECT [owners].[user_id] FROM [owners] WHERE [owners].[active]=@p1);",
+			args:   []interface{}{true},
+			query:  rel.From("users").Where(where.In("id", rel.Select("user_id").From("owners").Where(where.Eq("active", true)))),
+		},
+		{
+			result: "SELECT * FROM [sales].[orders] JOIN [crm].[customers] ON [crm].[customers].[id]=[sales].[orders].[customer_id] WHERE [sales].[orders].[id]=@p1;",
+			args:   []interface{}{1},
+			query:  rel.From("sales.orders").JoinOn("crm.customers", "crm.customers.id", "sales.orders.customer_id").Where(where.Eq("id", 1)),
+		},
+		{
+			result: "SELECT * FROM users WHERE id=@p1;",
+			args:   []interface{}{1},
+			query:  rel.Build("", rel.SQL("SELECT * FROM users WHERE id=@p1;", 1)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			statement, args := queryBuilder.Build(test.query)
+			assert.Equal(t, test.result, statement)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
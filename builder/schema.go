@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"strings"
+
+	"github.com/go-rel/rel"
+	"github.com/go-rel/sql/builder"
+)
+
+// Schema builder.
+type Schema struct {
+	BufferFactory builder.BufferFactory
+}
+
+// Build sql query for schema creation and deletion.
+func (s Schema) Build(op rel.SchemaOp, name string, optional bool) string {
+	buffer := s.BufferFactory.Create()
+
+	switch op {
+	case rel.SchemaCreate:
+		s.WriteCreateSchema(&buffer, name, optional)
+	case rel.SchemaDrop:
+		s.WriteDropSchema(&buffer, name, optional)
+	}
+
+	return buffer.String()
+}
+
+// WriteCreateSchema query to buffer.
+func (s Schema) WriteCreateSchema(buffer *builder.Buffer, name string, optional bool) {
+	if optional {
+		// CREATE SCHEMA must be the only statement in the batch.
+		buffer.WriteString("IF SCHEMA_ID(")
+		buffer.WriteString(buffer.Quoter.Value(name))
+		buffer.WriteString(") IS NULL EXEC(")
+		buffer.WriteString(buffer.Quoter.Value("CREATE SCHEMA " + buffer.Quoter.ID(name)))
+		buffer.WriteString(");")
+		return
+	}
+
+	buffer.WriteString("CREATE SCHEMA ")
+	buffer.WriteEscape(name)
+	buffer.WriteByte(';')
+}
+
+// WriteDropSchema query to buffer.
+func (s Schema) WriteDropSchema(buffer *builder.Buffer, name string, optional bool) {
+	if optional {
+		buffer.WriteString("IF SCHEMA_ID(")
+		buffer.WriteString(buffer.Quoter.Value(name))
+		buffer.WriteString(") IS NOT NULL ")
+	}
+
+	buffer.WriteString("DROP SCHEMA ")
+	buffer.WriteEscape(name)
+	buffer.WriteByte(';')
+}
+
+// quoteTable returns quoted and schema-qualified table name, e.g: [sales].[orders].
+func quoteTable(buffer *builder.Buffer, table string) string {
+	parts := strings.Split(table, ".")
+	for i := range parts {
+		parts[i] = buffer.Quoter.ID(strings.TrimSpace(parts[i]))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// splitTable returns schema and name of the table, schema is empty when the table is not qualified.
+func splitTable(table string) (string, string) {
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		return strings.TrimSpace(table[:i]), strings.TrimSpace(table[i+1:])
+	}
+
+	return "", table
+}
@@ -0,0 +1,49 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/rel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema_Build(t *testing.T) {
+	schemaBuilder := mssql.NewSchemaBuilder()
+
+	tests := []struct {
+		result   string
+		op       rel.SchemaOp
+		name     string
+		optional bool
+	}{
+		{
+			result: "CREATE SCHEMA [sales];",
+			op:     rel.SchemaCreate,
+			name:   "sales",
+		},
+		{
+			result:   "IF SCHEMA_ID('sales') IS NULL EXEC('CREATE SCHEMA [sales]');",
+			op:       rel.SchemaCreate,
+			name:     "sales",
+			optional: true,
+		},
+		{
+			result: "DROP SCHEMA [sales];",
+			op:     rel.SchemaDrop,
+			name:   "sales",
+		},
+		{
+			result:   "IF SCHEMA_ID('sales') IS NOT NULL DROP SCHEMA [sales];",
+			op:       rel.SchemaDrop,
+			name:     "sales",
+			optional: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			assert.Equal(t, test.result, schemaBuilder.Build(test.op, test.name, test.optional))
+		})
+	}
+}
@@ -1,12 +1,26 @@
 package builder
 
 import (
+	"errors"
+	"hash/fnv"
 	"strconv"
+	"strings"
 
 	"github.com/go-rel/rel"
 	"github.com/go-rel/sql/builder"
 )
 
+// dynamicSQL variable declared by alter table statement that needs to look up dependent objects.
+const dynamicSQL = "@sql"
+
+// defaultSQL variable declared by alter table statement that adds back default of altered column.
+const defaultSQL = "@default_sql"
+
+// KeyIfExists option of a dropped foreign key skips the drop when the constraint doesn't exist, e.g:
+//
+//	rel.Key{Op: rel.SchemaDrop, Type: rel.ForeignKey, Name: "fk_user", Options: builder.KeyIfExists}
+const KeyIfExists = "IF EXISTS"
+
 // Table builder.
 type Table struct {
 	BufferFactory builder.BufferFactory
@@ -32,6 +46,34 @@ func (t Table) Build(table rel.Table) string {
 	return buffer.String()
 }
 
+// Validate column definitions of table creation and modification, so size or precision that can't be
+// represented by SQL Server is rejected before the migration runs.
+func (t Table) Validate(table rel.Table) error {
+	if table.Op != rel.SchemaCreate && table.Op != rel.SchemaAlter {
+		return nil
+	}
+
+	for _, def := range table.Definitions {
+		column, ok := def.(rel.Column)
+		if !ok || (column.Op != rel.SchemaCreate && column.Op != rel.SchemaAlter) {
+			continue
+		}
+
+		if column.Op == rel.SchemaAlter && (column.Type == rel.ID || column.Type == rel.BigID) {
+			return errors.New("mssql: identity can't be added by altering column " + column.Name)
+		}
+
+		typ, m, n := t.ColumnMapper(&column)
+		if !validColumnType(typ, m, n) {
+			buffer := t.BufferFactory.Create()
+			t.WriteColumnType(&buffer, typ, m, n)
+			return errors.New("mssql: invalid type of column " + column.Name + ": " + buffer.String())
+		}
+	}
+
+	return nil
+}
+
 // WriteCreateTable query to buffer.
 func (t Table) WriteCreateTable(buffer *builder.Buffer, table rel.Table) {
 	if table.Optional {
@@ -52,7 +94,7 @@ func (t Table) WriteCreateTable(buffer *builder.Buffer, table rel.Table) {
 			}
 			switch v := def.(type) {
 			case rel.Column:
-				t.WriteColumn(buffer, v)
+				t.WriteColumnOf(buffer, table.Name, v)
 			case rel.Key:
 				t.WriteKey(buffer, v)
 			case rel.Raw:
@@ -69,57 +111,278 @@ func (t Table) WriteCreateTable(buffer *builder.Buffer, table rel.Table) {
 
 // WriteAlterTable query to buffer.
 func (t Table) WriteAlterTable(buffer *builder.Buffer, table rel.Table) {
+	var dynamic, restoreDefault bool
 	for _, def := range table.Definitions {
-		if v, ok := def.(rel.Column); ok && v.Op == rel.SchemaRename {
-			buffer.WriteString("EXEC sp_rename ")
-			buffer.WriteEscape(table.Name)
-			buffer.WriteString(", ")
-			buffer.WriteEscape(v.Name)
-			buffer.WriteString(", ")
-			buffer.WriteEscape(v.Rename)
-			buffer.WriteByte(';')
-			continue
+		dynamic = dynamic || requireDynamicSQL(def)
+		if column, ok := def.(rel.Column); ok && column.Op == rel.SchemaAlter && column.Default == nil {
+			restoreDefault = true
 		}
+	}
 
-		buffer.WriteString("ALTER TABLE ")
-		buffer.WriteEscape(table.Name)
-		buffer.WriteByte(' ')
+	if dynamic {
+		buffer.WriteString("DECLARE ")
+		buffer.WriteString(dynamicSQL)
+		buffer.WriteString(" NVARCHAR(MAX)")
+		if restoreDefault {
+			buffer.WriteString(", ")
+			buffer.WriteString(defaultSQL)
+			buffer.WriteString(" NVARCHAR(MAX)")
+		}
+		buffer.WriteByte(';')
+	}
 
+	for _, def := range table.Definitions {
 		switch v := def.(type) {
 		case rel.Column:
 			switch v.Op {
 			case rel.SchemaCreate:
-				buffer.WriteString("ADD ")
-				t.WriteColumn(buffer, v)
+				t.writeAlter(buffer, table, func() {
+					buffer.WriteString("ADD ")
+					t.WriteColumnOf(buffer, table.Name, v)
+				})
+			case rel.SchemaAlter:
+				t.writeAlterColumn(buffer, table, v)
+			case rel.SchemaRename:
+				t.WriteRenameColumn(buffer, table.Name, v)
 			case rel.SchemaDrop:
-				buffer.WriteString("DROP COLUMN ")
-				buffer.WriteEscape(v.Name)
+				t.writeDropDependencies(buffer, table.Name, v.Name)
+				t.writeAlter(buffer, table, func() {
+					buffer.WriteString("DROP COLUMN ")
+					buffer.WriteEscape(v.Name)
+				})
 			}
 		case rel.Key:
-			// TODO: Rename and Drop, PR welcome.
 			switch v.Op {
 			case rel.SchemaCreate:
-				buffer.WriteString("ADD ")
-				t.WriteKey(buffer, v)
+				t.writeAlter(buffer, table, func() {
+					buffer.WriteString("ADD ")
+					t.WriteKey(buffer, v)
+				})
+			case rel.SchemaRename:
+				t.WriteRenameKey(buffer, table.Name, v)
 			case rel.SchemaDrop:
-				buffer.WriteString("DROP ")
-				buffer.WriteString(t.DropKeyMapper(v.Type))
-				buffer.WriteString(" ")
-				buffer.WriteEscape(v.Name)
+				t.WriteDropKey(buffer, table, v)
 			}
+		case rel.Raw:
+			t.writeAlter(buffer, table, func() {
+				buffer.WriteString(string(v))
+			})
+		}
+	}
+}
+
+// writeAlter writes ALTER TABLE statement, the statement body is written by fn.
+func (t Table) writeAlter(buffer *builder.Buffer, table rel.Table, fn func()) {
+	buffer.WriteString("ALTER TABLE ")
+	buffer.WriteEscape(table.Name)
+	buffer.WriteByte(' ')
+	fn()
+	t.WriteOptions(buffer, table.Options)
+	buffer.WriteByte(';')
+}
+
+// WriteRenameKey query to buffer.
+//
+// Unique key might be backed by unique index instead of constraint, which is renamed as INDEX.
+func (t Table) WriteRenameKey(buffer *builder.Buffer, table string, key rel.Key) {
+	name := keyObjectName(buffer, table, key.Name)
+
+	if key.Type == rel.UniqueKey {
+		buffer.WriteString("IF OBJECT_ID(")
+		buffer.WriteString(buffer.Quoter.Value(name))
+		buffer.WriteString(", 'UQ') IS NULL EXEC sp_rename ")
+		buffer.WriteString(buffer.Quoter.Value(quoteTable(buffer, table) + "." + buffer.Quoter.ID(key.Name)))
+		buffer.WriteString(", ")
+		buffer.WriteString(buffer.Quoter.Value(key.Rename))
+		buffer.WriteString(", 'INDEX' ELSE ")
+	}
+
+	buffer.WriteString("EXEC sp_rename ")
+	buffer.WriteString(buffer.Quoter.Value(name))
+	buffer.WriteString(", ")
+	buffer.WriteString(buffer.Quoter.Value(key.Rename))
+	buffer.WriteString(", 'OBJECT';")
+}
+
+// WriteDropKey query to buffer, drop of foreign key is skipped when the constraint doesn't exist
+// and the key has KeyIfExists option.
+func (t Table) WriteDropKey(buffer *builder.Buffer, table rel.Table, key rel.Key) {
+	if key.Type == rel.ForeignKey && strings.EqualFold(strings.TrimSpace(key.Options), KeyIfExists) {
+		buffer.WriteString("IF OBJECT_ID(")
+		buffer.WriteString(buffer.Quoter.Value(keyObjectName(buffer, table.Name, key.Name)))
+		buffer.WriteString(") IS NOT NULL ")
+	}
+
+	t.writeAlter(buffer, table, func() {
+		buffer.WriteString("DROP ")
+		buffer.WriteString(t.DropKeyMapper(key.Type))
+		buffer.WriteByte(' ')
+		buffer.WriteEscape(key.Name)
+	})
+}
+
+// writeAlterColumn query to buffer, dynamic SQL variables are declared by WriteAlterTable.
+//
+// Default constraint blocks altering the column, so the existing default constraint is dropped and the new one
+// is added when the column has default value, otherwise the existing default is saved and added back.
+func (t Table) writeAlterColumn(buffer *builder.Buffer, table rel.Table, column rel.Column) {
+	if column.Default == nil {
+		t.writeSaveDefault(buffer, table.Name, column.Name)
+	}
+
+	t.writeDropDefault(buffer, table.Name, column.Name)
+
+	typ, m, n := t.ColumnMapper(&column)
+
+	buffer.WriteString("ALTER TABLE ")
+	buffer.WriteEscape(table.Name)
+	buffer.WriteString(" ALTER COLUMN ")
+	buffer.WriteEscape(column.Name)
+	buffer.WriteByte(' ')
+	t.WriteColumnType(buffer, typ, m, n)
+
+	// nullability is left as is when the mapped type already declares it.
+	if !strings.Contains(strings.ToUpper(typ), "NULL") {
+		if column.Required {
+			buffer.WriteString(" NOT NULL")
+		} else {
+			buffer.WriteString(" NULL")
 		}
+	}
+
+	t.WriteOptions(buffer, column.Options)
+	t.WriteOptions(buffer, table.Options)
+	buffer.WriteByte(';')
 
-		t.WriteOptions(buffer, table.Options)
+	if column.Default != nil {
+		buffer.WriteString("ALTER TABLE ")
+		buffer.WriteEscape(table.Name)
+		buffer.WriteString(" ADD CONSTRAINT ")
+		buffer.WriteEscape(defaultConstraintName(table.Name, column.Name))
+		buffer.WriteString(" DEFAULT ")
+		buffer.WriteValue(column.Default)
+		buffer.WriteString(" FOR ")
+		buffer.WriteEscape(column.Name)
+		buffer.WriteByte(';')
+	} else {
+		buffer.WriteString("IF ")
+		buffer.WriteString(defaultSQL)
+		buffer.WriteString(" IS NOT NULL EXEC sp_executesql ")
+		buffer.WriteString(defaultSQL)
 		buffer.WriteByte(';')
 	}
 }
 
+// writeSaveDefault query to buffer, which saves statement that adds the existing default constraint
+// of the column back with the same name and definition, the variable is NULL when there's no default.
+func (t Table) writeSaveDefault(buffer *builder.Buffer, table string, column string) {
+	buffer.WriteString("SET ")
+	buffer.WriteString(defaultSQL)
+	buffer.WriteString(" = NULL; SELECT ")
+	buffer.WriteString(defaultSQL)
+	buffer.WriteString(" = ")
+	buffer.WriteString(buffer.Quoter.Value("ALTER TABLE " + quoteTable(buffer, table) + " ADD CONSTRAINT "))
+	buffer.WriteString(" + QUOTENAME([name]) + ' DEFAULT ' + [definition] + ")
+	buffer.WriteString(buffer.Quoter.Value(" FOR " + buffer.Quoter.ID(column) + ";"))
+	buffer.WriteString(" FROM sys.default_constraints WHERE [parent_object_id] = ")
+	buffer.WriteString(objectID(buffer, table))
+	buffer.WriteString(" AND [parent_column_id] = ")
+	buffer.WriteString(columnID(buffer, table, column))
+	buffer.WriteByte(';')
+}
+
+// writeDropDefault query to buffer, which looks up and drops default constraint of the column
+// using dynamic SQL variable declared by WriteAlterTable.
+func (t Table) writeDropDefault(buffer *builder.Buffer, table string, column string) {
+	t.writeExecEach(buffer, "ALTER TABLE "+quoteTable(buffer, table)+" DROP CONSTRAINT ", "",
+		"sys.default_constraints WHERE [parent_object_id] = "+objectID(buffer, table)+
+			" AND [parent_column_id] = "+columnID(buffer, table, column))
+}
+
+// writeDropDependencies query to buffer, which looks up and drops default constraint, check constraints,
+// unique constraints and indexes that depend on the column, so the column can be dropped,
+// using dynamic SQL variable declared by WriteAlterTable.
+//
+// Only indexes and unique constraints of the column alone are dropped, the statement fails listing
+// indexes that also cover other columns, so indexes the migration doesn't mention are never lost.
+func (t Table) writeDropDependencies(buffer *builder.Buffer, table string, column string) {
+	var (
+		alter     = "ALTER TABLE " + quoteTable(buffer, table) + " DROP CONSTRAINT "
+		object    = objectID(buffer, table)
+		col       = columnID(buffer, table, column)
+		indexedBy = "[index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = " + object + " AND [column_id] = " + col + ")"
+	)
+
+	buffer.WriteString("SET ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" = NULL; SELECT ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" = COALESCE(")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" + ', ', ")
+	buffer.WriteString(buffer.Quoter.Value("Column " + buffer.Quoter.ID(column) + " can't be dropped, drop indexes that also cover other columns first: "))
+	buffer.WriteString(") + QUOTENAME([name]) FROM sys.indexes WHERE [object_id] = ")
+	buffer.WriteString(object)
+	buffer.WriteString(" AND [is_primary_key] = 0 AND ")
+	buffer.WriteString(indexedBy)
+	buffer.WriteString(" AND [index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = ")
+	buffer.WriteString(object)
+	buffer.WriteString(" AND [column_id] <> ")
+	buffer.WriteString(col)
+	buffer.WriteString("); IF ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" IS NOT NULL THROW 50000, ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(", 1;")
+
+	t.writeDropDefault(buffer, table, column)
+	t.writeExecEach(buffer, alter, "",
+		"sys.check_constraints WHERE [parent_object_id] = "+object+
+			" AND ([parent_column_id] = "+col+
+			" OR [object_id] IN (SELECT [referencing_id] FROM sys.sql_expression_dependencies WHERE [referenced_id] = "+object+
+			" AND [referenced_minor_id] = "+col+"))")
+	t.writeExecEach(buffer, alter, "",
+		"sys.indexes WHERE [object_id] = "+object+" AND [is_unique_constraint] = 1 AND "+indexedBy)
+	t.writeExecEach(buffer, "DROP INDEX ", " ON "+quoteTable(buffer, table),
+		"sys.indexes WHERE [object_id] = "+object+" AND [is_primary_key] = 0 AND [is_unique_constraint] = 0 AND "+indexedBy)
+}
+
+// writeExecEach executes statement for every object name selected from the given source.
+func (t Table) writeExecEach(buffer *builder.Buffer, prefix string, suffix string, source string) {
+	buffer.WriteString("SET ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" = N''; SELECT ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteString(" += ")
+	buffer.WriteString(buffer.Quoter.Value(prefix))
+	buffer.WriteString(" + QUOTENAME([name]) + ")
+	buffer.WriteString(buffer.Quoter.Value(suffix + ";"))
+	buffer.WriteString(" FROM ")
+	buffer.WriteString(source)
+	buffer.WriteString("; EXEC sp_executesql ")
+	buffer.WriteString(dynamicSQL)
+	buffer.WriteByte(';')
+}
+
+// WriteRenameColumn query to buffer.
+func (t Table) WriteRenameColumn(buffer *builder.Buffer, table string, column rel.Column) {
+	buffer.WriteString("EXEC sp_rename ")
+	buffer.WriteString(buffer.Quoter.Value(quoteTable(buffer, table) + "." + buffer.Quoter.ID(column.Name)))
+	buffer.WriteString(", ")
+	buffer.WriteString(buffer.Quoter.Value(column.Rename))
+	buffer.WriteString(", 'COLUMN';")
+}
+
 // WriteRenameTable query to buffer.
+//
+// The table stays in its schema, schema of the new name is ignored.
 func (t Table) WriteRenameTable(buffer *builder.Buffer, table rel.Table) {
+	_, rename := splitTable(table.Rename)
+
 	buffer.WriteString("EXEC sp_rename ")
-	buffer.WriteEscape(table.Name)
+	buffer.WriteString(buffer.Quoter.Value(quoteTable(buffer, table.Name)))
 	buffer.WriteString(", ")
-	buffer.WriteEscape(table.Rename)
+	buffer.WriteString(buffer.Quoter.Value(rename))
 	buffer.WriteByte(';')
 }
 
@@ -138,23 +401,18 @@ func (t Table) WriteDropTable(buffer *builder.Buffer, table rel.Table) {
 
 // WriteColumn definition to buffer.
 func (t Table) WriteColumn(buffer *builder.Buffer, column rel.Column) {
+	t.WriteColumnOf(buffer, "", column)
+}
+
+// WriteColumnOf definition of the table column to buffer.
+//
+// Default value is created as constraint named after the table, so it can be looked up by name.
+func (t Table) WriteColumnOf(buffer *builder.Buffer, table string, column rel.Column) {
 	typ, m, n := t.ColumnMapper(&column)
 
 	buffer.WriteEscape(column.Name)
 	buffer.WriteByte(' ')
-	buffer.WriteString(typ)
-
-	if m != 0 {
-		buffer.WriteByte('(')
-		buffer.WriteString(strconv.Itoa(m))
-
-		if n != 0 {
-			buffer.WriteByte(',')
-			buffer.WriteString(strconv.Itoa(n))
-		}
-
-		buffer.WriteByte(')')
-	}
+	t.WriteColumnType(buffer, typ, m, n)
 
 	if column.Unique {
 		buffer.WriteString(" UNIQUE")
@@ -169,6 +427,11 @@ func (t Table) WriteColumn(buffer *builder.Buffer, column rel.Column) {
 	}
 
 	if column.Default != nil {
+		if table != "" {
+			buffer.WriteString(" CONSTRAINT ")
+			buffer.WriteEscape(defaultConstraintName(table, column.Name))
+		}
+
 		buffer.WriteString(" DEFAULT ")
 		buffer.WriteValue(column.Default)
 	}
@@ -176,17 +439,33 @@ func (t Table) WriteColumn(buffer *builder.Buffer, column rel.Column) {
 	t.WriteOptions(buffer, column.Options)
 }
 
-// WriteKey definition to buffer.
-func (t Table) WriteKey(buffer *builder.Buffer, key rel.Key) {
-	typ := string(key.Type)
-
+// WriteColumnType to buffer.
+func (t Table) WriteColumnType(buffer *builder.Buffer, typ string, m int, n int) {
 	buffer.WriteString(typ)
 
+	if m != 0 {
+		buffer.WriteByte('(')
+		buffer.WriteString(strconv.Itoa(m))
+
+		if n != 0 {
+			buffer.WriteByte(',')
+			buffer.WriteString(strconv.Itoa(n))
+		}
+
+		buffer.WriteByte(')')
+	}
+}
+
+// WriteKey definition to buffer.
+func (t Table) WriteKey(buffer *builder.Buffer, key rel.Key) {
 	if key.Name != "" {
-		buffer.WriteByte(' ')
+		buffer.WriteString("CONSTRAINT ")
 		buffer.WriteEscape(key.Name)
+		buffer.WriteByte(' ')
 	}
 
+	buffer.WriteString(string(key.Type))
+
 	buffer.WriteString(" (")
 	for i, col := range key.Columns {
 		if i > 0 {
@@ -232,3 +511,74 @@ func (t Table) WriteOptions(buffer *builder.Buffer, options string) {
 	buffer.WriteByte(' ')
 	buffer.WriteString(options)
 }
+
+// validColumnType reports whether size or precision of the mapped column type is within SQL Server limits.
+func validColumnType(typ string, m int, n int) bool {
+	if m < 0 || n < 0 {
+		return false
+	}
+
+	switch typ {
+	case "NVARCHAR", "NCHAR":
+		return m <= 4000
+	case "VARCHAR", "CHAR", "VARBINARY", "BINARY":
+		return m <= 8000
+	case "DECIMAL", "NUMERIC":
+		// scale is only written along with precision.
+		return m <= 38 && n <= m
+	case "FLOAT":
+		return m <= 53
+	case "DATETIME2", "DATETIMEOFFSET", "TIME":
+		return m <= 7
+	}
+
+	return true
+}
+
+// requireDynamicSQL returns true when the definition drops dependent objects looked up at execution.
+func requireDynamicSQL(def rel.TableDefinition) bool {
+	column, ok := def.(rel.Column)
+	return ok && (column.Op == rel.SchemaAlter || column.Op == rel.SchemaDrop)
+}
+
+// keyObjectName returns quoted name of the constraint, which belongs to the schema of its table.
+func keyObjectName(buffer *builder.Buffer, table string, name string) string {
+	if schema, _ := splitTable(table); schema != "" {
+		return quoteTable(buffer, schema) + "." + buffer.Quoter.ID(name)
+	}
+
+	return buffer.Quoter.ID(name)
+}
+
+// defaultConstraintName of the column, e.g: DF_users_active_6f1c2a3b.
+// The hash of table and column name keeps names like table a_b column c and table a column b_c apart.
+func defaultConstraintName(table string, column string) string {
+	var (
+		_, name = splitTable(table)
+		hash    = fnv.New32a()
+	)
+
+	hash.Write([]byte(name + "\x00" + column))
+
+	var (
+		suffix = []rune("_" + strconv.FormatUint(uint64(hash.Sum32()), 16))
+		prefix = []rune("DF_" + name + "_" + column)
+	)
+
+	// identifier is limited to 128 characters.
+	if len(prefix)+len(suffix) > 128 {
+		prefix = prefix[:128-len(suffix)]
+	}
+
+	return string(prefix) + string(suffix)
+}
+
+// objectID returns OBJECT_ID expression of the table.
+func objectID(buffer *builder.Buffer, table string) string {
+	return "OBJECT_ID(" + buffer.Quoter.Value(quoteTable(buffer, table)) + ")"
+}
+
+// columnID returns COLUMNPROPERTY expression of the column id.
+func columnID(buffer *builder.Buffer, table string, column string) string {
+	return "COLUMNPROPERTY(" + objectID(buffer, table) + ", " + buffer.Quoter.Value(column) + ", 'ColumnId')"
+}
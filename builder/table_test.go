@@ -0,0 +1,448 @@
+package builder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-rel/mssql"
+	"github.com/go-rel/mssql/builder"
+	"github.com/go-rel/rel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_Build(t *testing.T) {
+	tableBuilder := mssql.NewTableBuilder()
+
+	tests := []struct {
+		result string
+		table  rel.Table
+	}{
+		{
+			result: "CREATE TABLE [products] ([id] INT NOT NULL IDENTITY(1,1) PRIMARY KEY, [name] NVARCHAR(100) UNIQUE NOT NULL, [description] NVARCHAR(MAX), [active] BIT CONSTRAINT [DF_products_active_a2a5d0c9] DEFAULT 1, [price] DECIMAL(10,2), [stock] BIGINT, [rating] FLOAT, [released_on] DATE, [created_at] DATETIMEOFFSET CONSTRAINT [DF_products_created_at_8ca1b74f] DEFAULT '2020-01-01 00:00:00', [opens_at] TIME);",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Name: "id", Type: rel.ID, Primary: true},
+					rel.Column{Name: "name", Type: rel.String, Limit: 100, Required: true, Unique: true},
+					rel.Column{Name: "description", Type: rel.Text},
+					rel.Column{Name: "active", Type: rel.Bool, Default: true},
+					rel.Column{Name: "price", Type: rel.Decimal, Precision: 10, Scale: 2},
+					rel.Column{Name: "stock", Type: rel.BigInt},
+					rel.Column{Name: "rating", Type: rel.Float},
+					rel.Column{Name: "released_on", Type: rel.Date},
+					rel.Column{Name: "created_at", Type: rel.DateTime, Default: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+					rel.Column{Name: "opens_at", Type: rel.Time},
+				},
+			},
+		},
+		{
+			result: "IF OBJECT_ID('[products]', 'U') IS NULL CREATE TABLE [products] ([id] BIGINT NOT NULL IDENTITY(1,1) PRIMARY KEY, [user_id] INT, [code] UNIQUEIDENTIFIER, FOREIGN KEY ([user_id]) REFERENCES [users] ([id]) ON DELETE CASCADE ON UPDATE NO ACTION, CHECK ([id] > 0));",
+			table: rel.Table{
+				Op:       rel.SchemaCreate,
+				Name:     "products",
+				Optional: true,
+				Definitions: []rel.TableDefinition{
+					rel.Column{Name: "id", Type: rel.BigID, Primary: true},
+					rel.Column{Name: "user_id", Type: rel.Int},
+					rel.Column{Name: "code", Type: "UNIQUEIDENTIFIER"},
+					rel.Key{Columns: []string{"user_id"}, Type: rel.ForeignKey, Reference: rel.ForeignKeyReference{Table: "users", Columns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"}},
+					rel.Raw("CHECK ([id] > 0)"),
+				},
+			},
+		},
+		{
+			result: "ALTER TABLE [products] ADD [sku] NVARCHAR(20) NOT NULL CONSTRAINT [DF_products_sku_3a2562b0] DEFAULT '';",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "sku", Type: rel.String, Limit: 20, Required: true, Default: ""},
+				},
+			},
+		},
+		{
+			result: "EXEC sp_rename '[products].[sku]', 'code', 'COLUMN';",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaRename, Name: "sku", Rename: "code"},
+				},
+			},
+		},
+		{
+			// existing default blocks altering the column, it's saved, dropped and added back.
+			result: "DECLARE @sql NVARCHAR(MAX), @default_sql NVARCHAR(MAX);" +
+				"SET @default_sql = NULL; SELECT @default_sql = 'ALTER TABLE [products] ADD CONSTRAINT ' + QUOTENAME([name]) + ' DEFAULT ' + [definition] + ' FOR [sku];' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId');" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId'); EXEC sp_executesql @sql;" +
+				"ALTER TABLE [products] ALTER COLUMN [sku] NVARCHAR(50) NOT NULL;" +
+				"IF @default_sql IS NOT NULL EXEC sp_executesql @default_sql;" +
+				"SET @default_sql = NULL; SELECT @default_sql = 'ALTER TABLE [products] ADD CONSTRAINT ' + QUOTENAME([name]) + ' DEFAULT ' + [definition] + ' FOR [price];' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'price', 'ColumnId');" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'price', 'ColumnId'); EXEC sp_executesql @sql;" +
+				"ALTER TABLE [products] ALTER COLUMN [price] DECIMAL(12,2) NULL;" +
+				"IF @default_sql IS NOT NULL EXEC sp_executesql @default_sql;",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaAlter, Name: "sku", Type: rel.String, Limit: 50, Required: true},
+					rel.Column{Op: rel.SchemaAlter, Name: "price", Type: rel.Decimal, Precision: 12, Scale: 2},
+				},
+			},
+		},
+		{
+			result: "DECLARE @sql NVARCHAR(MAX);SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'active', 'ColumnId'); EXEC sp_executesql @sql;ALTER TABLE [products] ALTER COLUMN [active] BIT NOT NULL;ALTER TABLE [products] ADD CONSTRAINT [DF_products_active_a2a5d0c9] DEFAULT 1 FOR [active];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaAlter, Name: "active", Type: rel.Bool, Required: true, Default: true},
+				},
+			},
+		},
+		{
+			// indexes that also cover other columns fail the statement, default, check, unique constraints
+			// and indexes of the column alone are dropped first.
+			result: "DECLARE @sql NVARCHAR(MAX);" +
+				"SET @sql = NULL; SELECT @sql = COALESCE(@sql + ', ', 'Column [sku] can''t be dropped, drop indexes that also cover other columns first: ') + QUOTENAME([name]) FROM sys.indexes WHERE [object_id] = OBJECT_ID('[products]') AND [is_primary_key] = 0 AND [index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = OBJECT_ID('[products]') AND [column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId')) AND [index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = OBJECT_ID('[products]') AND [column_id] <> COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId')); IF @sql IS NOT NULL THROW 50000, @sql, 1;" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId'); EXEC sp_executesql @sql;" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.check_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND ([parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId') OR [object_id] IN (SELECT [referencing_id] FROM sys.sql_expression_dependencies WHERE [referenced_id] = OBJECT_ID('[products]') AND [referenced_minor_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId'))); EXEC sp_executesql @sql;" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.indexes WHERE [object_id] = OBJECT_ID('[products]') AND [is_unique_constraint] = 1 AND [index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = OBJECT_ID('[products]') AND [column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId')); EXEC sp_executesql @sql;" +
+				"SET @sql = N''; SELECT @sql += 'DROP INDEX ' + QUOTENAME([name]) + ' ON [products];' FROM sys.indexes WHERE [object_id] = OBJECT_ID('[products]') AND [is_primary_key] = 0 AND [is_unique_constraint] = 0 AND [index_id] IN (SELECT [index_id] FROM sys.index_columns WHERE [object_id] = OBJECT_ID('[products]') AND [column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId')); EXEC sp_executesql @sql;" +
+				"ALTER TABLE [products] DROP COLUMN [sku];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaDrop, Name: "sku"},
+				},
+			},
+		},
+		{
+			result: "ALTER TABLE [products] ADD FOREIGN KEY ([user_id]) REFERENCES [users] ([id]);",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaCreate, Type: rel.ForeignKey, Columns: []string{"user_id"}, Reference: rel.ForeignKeyReference{Table: "users", Columns: []string{"id"}}},
+				},
+			},
+		},
+		{
+			result: "ALTER TABLE [products] DROP CONSTRAINT [fk_user];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaDrop, Name: "fk_user", Type: rel.ForeignKey},
+				},
+			},
+		},
+		{
+			result: "ALTER TABLE [products] ADD CONSTRAINT [fk_user] FOREIGN KEY ([user_id]) REFERENCES [users] ([id]) ON DELETE CASCADE;ALTER TABLE [products] ADD CONSTRAINT [uq_sku] UNIQUE ([sku]);",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaCreate, Name: "fk_user", Type: rel.ForeignKey, Columns: []string{"user_id"}, Reference: rel.ForeignKeyReference{Table: "users", Columns: []string{"id"}, OnDelete: "CASCADE"}},
+					rel.Key{Op: rel.SchemaCreate, Name: "uq_sku", Type: rel.UniqueKey, Columns: []string{"sku"}},
+				},
+			},
+		},
+		{
+			result: "EXEC sp_rename '[fk_user]', 'fk_owner', 'OBJECT';EXEC sp_rename '[pk_products]', 'pk_items', 'OBJECT';",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaRename, Name: "fk_user", Rename: "fk_owner", Type: rel.ForeignKey},
+					rel.Key{Op: rel.SchemaRename, Name: "pk_products", Rename: "pk_items", Type: rel.PrimaryKey},
+				},
+			},
+		},
+		{
+			// unique key created using unique index is renamed as INDEX.
+			result: "IF OBJECT_ID('[uq_sku]', 'UQ') IS NULL EXEC sp_rename '[products].[uq_sku]', 'uq_code', 'INDEX' ELSE EXEC sp_rename '[uq_sku]', 'uq_code', 'OBJECT';",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaRename, Name: "uq_sku", Rename: "uq_code", Type: rel.UniqueKey},
+				},
+			},
+		},
+		{
+			result: "IF OBJECT_ID('[fk_user]') IS NOT NULL ALTER TABLE [products] DROP CONSTRAINT [fk_user];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaDrop, Name: "fk_user", Type: rel.ForeignKey, Options: builder.KeyIfExists},
+				},
+			},
+		},
+		{
+			// only foreign key drop can be skipped.
+			result: "ALTER TABLE [products] DROP CONSTRAINT [uq_sku];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Key{Op: rel.SchemaDrop, Name: "uq_sku", Type: rel.UniqueKey, Options: builder.KeyIfExists},
+				},
+			},
+		},
+		{
+			result: "ALTER TABLE [products] ADD CONSTRAINT [ck_price] CHECK ([price] > 0);",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Raw("ADD CONSTRAINT [ck_price] CHECK ([price] > 0)"),
+				},
+			},
+		},
+		{
+			result: "EXEC sp_rename '[products]', 'items';",
+			table:  rel.Table{Op: rel.SchemaRename, Name: "products", Rename: "items"},
+		},
+		{
+			result: "IF OBJECT_ID('[sales].[orders]', 'U') IS NULL CREATE TABLE [sales].[orders] ([id] INT NOT NULL IDENTITY(1,1) PRIMARY KEY, [status] NVARCHAR(20) CONSTRAINT [DF_orders_status_e6691a8e] DEFAULT 'open', [customer_id] INT, CONSTRAINT [fk_customer] FOREIGN KEY ([customer_id]) REFERENCES [crm].[customers] ([id]));",
+			table: rel.Table{
+				Op:       rel.SchemaCreate,
+				Name:     "sales.orders",
+				Optional: true,
+				Definitions: []rel.TableDefinition{
+					rel.Column{Name: "id", Type: rel.ID, Primary: true},
+					rel.Column{Name: "status", Type: rel.String, Limit: 20, Default: "open"},
+					rel.Column{Name: "customer_id", Type: rel.Int},
+					rel.Key{Name: "fk_customer", Type: rel.ForeignKey, Columns: []string{"customer_id"}, Reference: rel.ForeignKeyReference{Table: "crm.customers", Columns: []string{"id"}}},
+				},
+			},
+		},
+		{
+			result: "EXEC sp_rename '[sales].[orders].[status]', 'state', 'COLUMN';" +
+				"EXEC sp_rename '[sales].[fk_customer]', 'fk_client', 'OBJECT';" +
+				"IF OBJECT_ID('[sales].[uq_code]', 'UQ') IS NULL EXEC sp_rename '[sales].[orders].[uq_code]', 'uq_number', 'INDEX' ELSE EXEC sp_rename '[sales].[uq_code]', 'uq_number', 'OBJECT';" +
+				"IF OBJECT_ID('[sales].[fk_customer]') IS NOT NULL ALTER TABLE [sales].[orders] DROP CONSTRAINT [fk_customer];",
+			table: rel.Table{
+				Op:       rel.SchemaAlter,
+				Name:     "sales.orders",
+				Optional: true,
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaRename, Name: "status", Rename: "state"},
+					rel.Key{Op: rel.SchemaRename, Name: "fk_customer", Rename: "fk_client", Type: rel.ForeignKey},
+					rel.Key{Op: rel.SchemaRename, Name: "uq_code", Rename: "uq_number", Type: rel.UniqueKey},
+					rel.Key{Op: rel.SchemaDrop, Name: "fk_customer", Type: rel.ForeignKey, Options: builder.KeyIfExists},
+				},
+			},
+		},
+		{
+			result: "DECLARE @sql NVARCHAR(MAX);" +
+				"SET @sql = N''; SELECT @sql += 'ALTER TABLE [sales].[orders] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[sales].[orders]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[sales].[orders]'), 'status', 'ColumnId'); EXEC sp_executesql @sql;" +
+				"ALTER TABLE [sales].[orders] ALTER COLUMN [status] NVARCHAR(30) NULL;" +
+				"ALTER TABLE [sales].[orders] ADD CONSTRAINT [DF_orders_status_e6691a8e] DEFAULT 'new' FOR [status];",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "sales.orders",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaAlter, Name: "status", Type: rel.String, Limit: 30, Default: "new"},
+				},
+			},
+		},
+		{
+			result: "EXEC sp_rename '[sales].[orders]', 'purchases';",
+			table:  rel.Table{Op: rel.SchemaRename, Name: "sales.orders", Rename: "sales.purchases"},
+		},
+		{
+			result: "IF OBJECT_ID('[sales].[orders]', 'U') IS NOT NULL DROP TABLE [sales].[orders];",
+			table:  rel.Table{Op: rel.SchemaDrop, Name: "sales.orders", Optional: true},
+		},
+		{
+			result: "DROP TABLE [products];",
+			table:  rel.Table{Op: rel.SchemaDrop, Name: "products"},
+		},
+		{
+			result: "IF OBJECT_ID('[products]', 'U') IS NOT NULL DROP TABLE [products];",
+			table:  rel.Table{Op: rel.SchemaDrop, Name: "products", Optional: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			assert.Equal(t, test.result, tableBuilder.Build(test.table))
+		})
+	}
+}
+
+func TestTable_Validate(t *testing.T) {
+	tableBuilder := mssql.NewTableBuilder()
+
+	tests := []struct {
+		result string
+		table  rel.Table
+	}{
+		{
+			result: "",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "name", Type: rel.String, Limit: 5000},
+					rel.Column{Op: rel.SchemaCreate, Name: "price", Type: rel.Decimal, Precision: 38, Scale: 38},
+					rel.Column{Op: rel.SchemaCreate, Name: "rating", Type: rel.Float, Precision: 53},
+					rel.Column{Op: rel.SchemaCreate, Name: "created_at", Type: rel.DateTime, Precision: 7},
+					rel.Key{Op: rel.SchemaCreate, Type: rel.PrimaryKey, Columns: []string{"name"}},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column price: DECIMAL(39,2)",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "price", Type: rel.Decimal, Precision: 39, Scale: 2},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column price: DECIMAL(10,12)",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaAlter, Name: "price", Type: rel.Decimal, Precision: 10, Scale: 12},
+				},
+			},
+		},
+		{
+			// precision defaults to 18 when only scale is set.
+			result: "",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "price", Type: rel.Decimal, Scale: 2},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column price: DECIMAL(18,20)",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "price", Type: rel.Decimal, Scale: 20},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column rating: FLOAT(54)",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "rating", Type: rel.Float, Precision: 54},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column created_at: DATETIMEOFFSET(9)",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "created_at", Type: rel.DateTime, Precision: 9},
+				},
+			},
+		},
+		{
+			result: "mssql: invalid type of column code: NCHAR(4001)",
+			table: rel.Table{
+				Op:   rel.SchemaCreate,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaCreate, Name: "code", Type: mssql.NChar, Limit: 4001},
+				},
+			},
+		},
+		{
+			result: "mssql: identity can't be added by altering column id",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaAlter, Name: "id", Type: rel.BigID},
+				},
+			},
+		},
+		{
+			result: "",
+			table: rel.Table{
+				Op:   rel.SchemaAlter,
+				Name: "products",
+				Definitions: []rel.TableDefinition{
+					rel.Column{Op: rel.SchemaDrop, Name: "price", Type: rel.Decimal, Precision: 39},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			err := tableBuilder.Validate(test.table)
+			if test.result == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.result)
+			}
+		})
+	}
+}
+
+func TestTable_Build_defaultConstraintName(t *testing.T) {
+	var (
+		tableBuilder = mssql.NewTableBuilder()
+		first        = tableBuilder.Build(rel.Table{Op: rel.SchemaAlter, Name: "a_b", Definitions: []rel.TableDefinition{
+			rel.Column{Op: rel.SchemaCreate, Name: "c", Type: rel.Int, Default: 0},
+		}})
+		second = tableBuilder.Build(rel.Table{Op: rel.SchemaAlter, Name: "a", Definitions: []rel.TableDefinition{
+			rel.Column{Op: rel.SchemaCreate, Name: "b_c", Type: rel.Int, Default: 0},
+		}})
+	)
+
+	assert.Equal(t, "ALTER TABLE [a_b] ADD [c] INT CONSTRAINT [DF_a_b_c_e4161480] DEFAULT 0;", first)
+	assert.Equal(t, "ALTER TABLE [a] ADD [b_c] INT CONSTRAINT [DF_a_b_c_f5cbf2fc] DEFAULT 0;", second)
+}
+
+func TestTable_WriteColumn(t *testing.T) {
+	var (
+		tableBuilder = mssql.NewTableBuilder()
+		buffer       = tableBuilder.BufferFactory.Create()
+	)
+
+	tableBuilder.WriteColumn(&buffer, rel.Column{Name: "active", Type: rel.Bool, Default: true})
+	assert.Equal(t, "[active] BIT DEFAULT 1", buffer.String())
+}
+
+func TestTable_Build_alterColumnNotNullType(t *testing.T) {
+	tableBuilder := mssql.NewTableBuilder()
+	tableBuilder.ColumnMapper = func(column *rel.Column) (string, int, int) {
+		return "NVARCHAR(10) NOT NULL", 0, 0
+	}
+
+	assert.Equal(t, "DECLARE @sql NVARCHAR(MAX), @default_sql NVARCHAR(MAX);"+
+		"SET @default_sql = NULL; SELECT @default_sql = 'ALTER TABLE [products] ADD CONSTRAINT ' + QUOTENAME([name]) + ' DEFAULT ' + [definition] + ' FOR [sku];' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId');"+
+		"SET @sql = N''; SELECT @sql += 'ALTER TABLE [products] DROP CONSTRAINT ' + QUOTENAME([name]) + ';' FROM sys.default_constraints WHERE [parent_object_id] = OBJECT_ID('[products]') AND [parent_column_id] = COLUMNPROPERTY(OBJECT_ID('[products]'), 'sku', 'ColumnId'); EXEC sp_executesql @sql;"+
+		"ALTER TABLE [products] ALTER COLUMN [sku] NVARCHAR(10) NOT NULL;"+
+		"IF @default_sql IS NOT NULL EXEC sp_executesql @default_sql;",
+		tableBuilder.Build(rel.Table{
+			Op:   rel.SchemaAlter,
+			Name: "products",
+			Definitions: []rel.TableDefinition{
+				rel.Column{Op: rel.SchemaAlter, Name: "sku", Type: rel.String, Required: true},
+			},
+		}))
+}
@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"github.com/go-rel/rel"
+	"github.com/go-rel/sql/builder"
+)
+
+// Update builder.
+type Update struct {
+	BufferFactory builder.BufferFactory
+	Query         builder.QueryWriter
+	Filter        builder.Filter
+}
+
+// Build SQL string and it arguments.
+func (u Update) Build(table string, primaryField string, mutates map[string]rel.Mutate, filter rel.FilterQuery) (string, []interface{}) {
+	return u.BuildOutput(table, primaryField, mutates, filter, nil)
+}
+
+// BuildOutput builds SQL string and it arguments, with OUTPUT clause that returns updated fields.
+func (u Update) BuildOutput(table string, primaryField string, mutates map[string]rel.Mutate, filter rel.FilterQuery, output []string) (string, []interface{}) {
+	buffer := u.BufferFactory.Create()
+
+	buffer.WriteString("UPDATE ")
+	buffer.WriteTable(table)
+	buffer.WriteString(" SET ")
+
+	i := 0
+	for field, mut := range mutates {
+		if field == primaryField {
+			continue
+		}
+
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		i++
+
+		switch mut.Type {
+		case rel.ChangeSetOp:
+			buffer.WriteEscape(field)
+			buffer.WriteByte('=')
+			buffer.WriteValue(mut.Value)
+		case rel.ChangeIncOp:
+			buffer.WriteEscape(field)
+			buffer.WriteByte('=')
+			buffer.WriteEscape(field)
+			buffer.WriteByte('+')
+			buffer.WriteValue(mut.Value)
+		case rel.ChangeFragmentOp:
+			buffer.WriteString(field)
+			buffer.AddArguments(mut.Value.([]interface{})...)
+		}
+	}
+
+	writeOutput(&buffer, "INSERTED", output)
+
+	if !filter.None() {
+		buffer.WriteString(" WHERE ")
+		u.Filter.Write(&buffer, table, filter, u.Query)
+	}
+
+	buffer.WriteString(";")
+
+	return buffer.String(), buffer.Arguments()
+}
@@ -0,0 +1,143 @@
+package mssql
+
+import (
+	"context"
+	db "database/sql"
+	"encoding/json"
+
+	"github.com/go-rel/rel"
+)
+
+// BulkOptions for BulkInsert.
+type BulkOptions struct {
+	// BatchSize is the number of rows hinted to server for each batch (ROWS_PER_BATCH).
+	// It's only a hint for the query optimizer, the rows are still copied as a single batch.
+	BatchSize int
+	// KilobytesPerBatch is the approximate size of each batch (KILOBYTES_PER_BATCH).
+	KilobytesPerBatch int
+	// CheckConstraints enables constraint checking during the load (CHECK_CONSTRAINTS).
+	CheckConstraints bool
+	// FireTriggers executes insert triggers of the table (FIRE_TRIGGERS).
+	FireTriggers bool
+	// KeepNulls keeps null values instead of applying column defaults (KEEP_NULLS).
+	KeepNulls bool
+	// Tablock acquires bulk update table lock for the duration of the load (TABLOCK).
+	Tablock bool
+	// Order of the rows according to the clustered index (ORDER).
+	Order []string
+}
+
+// BulkInsert streams entities into their table using TDS bulk copy protocol and returns the number of copied rows.
+//
+// Unlike InsertAll, generated primary values are not returned and associations are not saved.
+// Values of identity column are always generated by server, since the driver can't request KEEP_IDENTITY,
+// use InsertAll to insert explicit identity values.
+// Bulk copy is executed inside current transaction when the adapter is a transaction,
+// use repo.Adapter(ctx) to retrieve the transaction adapter inside rel.Repository.Transaction.
+func (m MSSQL) BulkInsert(ctx context.Context, entities interface{}, options BulkOptions) (int64, error) {
+	var (
+		col         = rel.NewCollection(entities)
+		table       = qualifyTable(m.schema(ctx), col.Table())
+		fields      []string
+		fieldMap    = make(map[string]struct{})
+		bulkMutates = make([]map[string]rel.Mutate, col.Len())
+	)
+
+	if col.Len() == 0 {
+		return 0, nil
+	}
+
+	for i := range bulkMutates {
+		mutation := rel.Apply(col.Get(i))
+		for field := range mutation.Mutates {
+			if _, exist := fieldMap[field]; !exist {
+				fieldMap[field] = struct{}{}
+				fields = append(fields, field)
+			}
+		}
+		bulkMutates[i] = mutation.Mutates
+	}
+
+	var (
+		err       error
+		count     int64
+		statement = copyIn(table, options, fields)
+		finish    = m.Instrumenter.Observe(ctx, "adapter-bulk-insert", statement)
+	)
+
+	if m.Tx != nil {
+		count, err = m.bulkCopy(ctx, m.Tx.PrepareContext, statement, fields, bulkMutates)
+	} else {
+		var conn *db.Conn
+		if conn, err = m.DB.Conn(ctx); err == nil {
+			// bulk copy must stay on a single connection.
+			count, err = m.bulkCopy(ctx, conn.PrepareContext, statement, fields, bulkMutates)
+			conn.Close()
+		}
+	}
+
+	finish(err)
+
+	return count, m.ErrorMapper(err)
+}
+
+func (m MSSQL) bulkCopy(ctx context.Context, prepare func(context.Context, string) (*db.Stmt, error), statement string, fields []string, bulkMutates []map[string]rel.Mutate) (int64, error) {
+	stmt, err := prepare(ctx, statement)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(fields))
+	for _, mutates := range bulkMutates {
+		for i, field := range fields {
+			values[i] = nil
+			if mut, ok := mutates[field]; ok && mut.Type == rel.ChangeSetOp {
+				values[i] = mut.Value
+			}
+		}
+
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, err
+		}
+	}
+
+	// executing without arguments flushes the remaining rows.
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// bulkCopyConfig of INSERTBULK statement, which is recognized by both microsoft and denisenkom driver.
+type bulkCopyConfig struct {
+	TableName   string
+	ColumnsName []string
+	Options     struct {
+		CheckConstraints  bool
+		FireTriggers      bool
+		KeepNulls         bool
+		KilobytesPerBatch int
+		RowsPerBatch      int
+		Order             []string
+		Tablock           bool
+	}
+}
+
+// copyIn returns bulk copy statement the same way driver's CopyIn does, without importing the driver.
+func copyIn(table string, options BulkOptions, fields []string) string {
+	config := bulkCopyConfig{TableName: table, ColumnsName: fields}
+	config.Options.CheckConstraints = options.CheckConstraints
+	config.Options.FireTriggers = options.FireTriggers
+	config.Options.KeepNulls = options.KeepNulls
+	config.Options.KilobytesPerBatch = options.KilobytesPerBatch
+	config.Options.RowsPerBatch = options.BatchSize
+	config.Options.Order = options.Order
+	config.Options.Tablock = options.Tablock
+
+	// config only consists of strings, numbers and booleans, which can always be encoded.
+	b, _ := json.Marshal(config)
+	return "INSERTBULK " + string(b)
+}
@@ -4,8 +4,14 @@ package mssql
 import (
 	"context"
 	db "database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	mssqlbuilder "github.com/go-rel/mssql/builder"
 	"github.com/go-rel/rel"
@@ -16,6 +22,27 @@ import (
 // MSSQL Adapter.
 type MSSQL struct {
 	sql.SQL
+
+	// IsolationLevel used when beginning a transaction, can be overridden per transaction using WithIsolationLevel.
+	// When READ_COMMITTED_SNAPSHOT database option is enabled, sql.LevelReadCommitted uses row versioning.
+	IsolationLevel db.IsolationLevel
+
+	// RetryPolicy for deadlock victims and transient errors, disabled by default.
+	RetryPolicy RetryPolicy
+
+	// Schema qualifies every unqualified table, can be overridden per context using WithSchema.
+	Schema string
+
+	conn *db.Conn
+}
+
+type isolationLevelKey struct{}
+
+// WithIsolationLevel returns context that begins transaction using the given isolation level,
+// including sql.LevelSnapshot for SQL Server SNAPSHOT isolation.
+// The isolation level only applies to the outermost transaction.
+func WithIsolationLevel(ctx context.Context, level db.IsolationLevel) context.Context {
+	return context.WithValue(ctx, isolationLevelKey{}, level)
 }
 
 // Name of database type this adapter implements.
@@ -24,61 +51,644 @@ const Name string = "mssql"
 var _ rel.Adapter = (*MSSQL)(nil)
 
 // Begin begins a new transaction.
+//
+// Calling Begin inside a transaction creates a savepoint using SAVE TRANSACTION,
+// so only the nested unit of work is reverted when it's rolled back.
 func (m MSSQL) Begin(ctx context.Context) (rel.Adapter, error) {
-	txSql, err := m.SQL.Begin(ctx)
+	if m.Tx == nil {
+		level := m.IsolationLevel
+		if l, ok := ctx.Value(isolationLevelKey{}).(db.IsolationLevel); ok {
+			level = l
+		}
+
+		if level != db.LevelDefault {
+			return m.beginIsolated(ctx, level)
+		}
+
+		txSql, err := m.SQL.Begin(ctx)
+
+		m.SQL = *txSql.(*sql.SQL)
+		return &m, err
+	}
+
+	var (
+		savepoint = m.Savepoint + 1
+		finish    = m.Instrumenter.Observe(ctx, "adapter-begin", "begin transaction")
+		_, err    = m.Tx.ExecContext(ctx, "SAVE TRANSACTION "+savepointName(savepoint)+";")
+	)
+
+	finish(err)
+
+	m.Savepoint = savepoint
+	return &m, m.ErrorMapper(err)
+}
+
+// beginIsolated begins transaction on a dedicated connection,
+// so the isolation level can be reset before the connection is returned to the pool.
+func (m MSSQL) beginIsolated(ctx context.Context, level db.IsolationLevel) (rel.Adapter, error) {
+	var (
+		conn   *db.Conn
+		tx     *db.Tx
+		err    error
+		finish = m.Instrumenter.Observe(ctx, "adapter-begin", "begin transaction")
+	)
+
+	if conn, err = m.DB.Conn(ctx); err == nil {
+		if tx, err = conn.BeginTx(ctx, &db.TxOptions{Isolation: level}); err != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+
+	finish(err)
+
+	m.Tx = tx
+	m.Savepoint = 0
+	m.conn = conn
+	return &m, m.ErrorMapper(err)
+}
+
+// release resets isolation level of the dedicated connection and returns it to the pool.
+//
+// The reset doesn't use context of the transaction, which may already be canceled,
+// and the connection is discarded when the reset fails, so the isolation level never leaks to the pool.
+func (m MSSQL) release() {
+	if m.conn == nil {
+		return
+	}
+
+	if _, err := m.conn.ExecContext(context.Background(), "SET TRANSACTION ISOLATION LEVEL READ COMMITTED;"); err != nil {
+		m.conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+
+	m.conn.Close()
+}
+
+// Commit commits current transaction.
+//
+// SQL Server doesn't support releasing savepoint, changes of a nested transaction
+// are committed along with its outermost transaction.
+func (m MSSQL) Commit(ctx context.Context) error {
+	if m.Tx == nil || m.Savepoint == 0 {
+		defer m.release()
+		return m.SQL.Commit(ctx)
+	}
+
+	finish := m.Instrumenter.Observe(ctx, "adapter-commit", "commit transaction")
+	finish(nil)
+
+	return nil
+}
+
+// Rollback revert current transaction.
+//
+// Nested transaction is rolled back to its savepoint, leaving the outer transaction active.
+func (m MSSQL) Rollback(ctx context.Context) error {
+	if m.Tx == nil || m.Savepoint == 0 {
+		defer m.release()
+		return m.SQL.Rollback(ctx)
+	}
+
+	var (
+		finish = m.Instrumenter.Observe(ctx, "adapter-rollback", "rollback transaction")
+		_, err = m.Tx.ExecContext(ctx, "ROLLBACK TRANSACTION "+savepointName(m.Savepoint)+";")
+	)
+
+	finish(err)
+
+	return m.ErrorMapper(err)
+}
+
+func savepointName(savepoint int) string {
+	return "s" + strconv.Itoa(savepoint)
+}
+
+// Query performs query operation.
+func (m MSSQL) Query(ctx context.Context, query rel.Query) (rel.Cursor, error) {
+	var cursor rel.Cursor
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	err := m.retryRead(ctx, func() (err error) {
+		cursor, err = m.SQL.Query(ctx, query)
+		return err
+	})
+
+	return cursor, err
+}
+
+// Apply performs migration to database.
+func (m MSSQL) Apply(ctx context.Context, migration rel.Migration) error {
+	switch v := migration.(type) {
+	case rel.Table:
+		if tableBuilder, ok := m.TableBuilder.(mssqlbuilder.Table); ok {
+			if err := tableBuilder.Validate(v); err != nil {
+				return err
+			}
+		}
+	case rel.Index:
+		if indexBuilder, ok := m.IndexBuilder.(mssqlbuilder.Index); ok {
+			if err := indexBuilder.Validate(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.SQL.Apply(ctx, qualifyMigration(m.schema(ctx), migration))
+}
+
+// Aggregate record using given query.
+func (m MSSQL) Aggregate(ctx context.Context, query rel.Query, mode string, field string) (int, error) {
+	var result int
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	err := m.retryRead(ctx, func() (err error) {
+		result, err = m.SQL.Aggregate(ctx, query, mode, field)
+		return err
+	})
+
+	return result, err
+}
+
+// Update updates a record in database.
+func (m MSSQL) Update(ctx context.Context, query rel.Query, primaryField string, mutates map[string]rel.Mutate) (int, error) {
+	var updatedCount int
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	err := m.retryWrite(ctx, func() (err error) {
+		updatedCount, err = m.SQL.Update(ctx, query, primaryField, mutates)
+		return err
+	})
+
+	return updatedCount, err
+}
+
+// Delete deletes all results that match the query.
+func (m MSSQL) Delete(ctx context.Context, query rel.Query) (int, error) {
+	var deletedCount int
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	err := m.retryWrite(ctx, func() (err error) {
+		deletedCount, err = m.SQL.Delete(ctx, query)
+		return err
+	})
 
-	return &MSSQL{SQL: *txSql.(*sql.SQL)}, err
+	return deletedCount, err
+}
+
+type outputUpdateBuilder interface {
+	BuildOutput(table string, primaryField string, mutates map[string]rel.Mutate, filter rel.FilterQuery, output []string) (string, []interface{})
+}
+
+type outputDeleteBuilder interface {
+	BuildOutput(table string, filter rel.FilterQuery, output []string) (string, []interface{})
+}
+
+// UpdateAnyOutput updates records that match the query and scans the updated rows into entities using OUTPUT clause.
+// Entities must be a pointer to slice, table of the entities is used when the query doesn't specify one.
+func (m MSSQL) UpdateAnyOutput(ctx context.Context, entities interface{}, query rel.Query, mutates ...rel.Mutate) (int, error) {
+	updateBuilder, ok := m.UpdateBuilder.(outputUpdateBuilder)
+	if !ok {
+		return 0, errors.New("mssql: update builder doesn't support OUTPUT clause")
+	}
+
+	var (
+		col  = rel.NewCollection(entities)
+		muts = make(map[string]rel.Mutate, len(mutates))
+	)
+
+	for _, mut := range mutates {
+		muts[mut.Field] = mut
+	}
+
+	if query.Table == "" {
+		query.Table = col.Table()
+	}
+
+	query = qualifyQuery(m.schema(ctx), query)
+	statement, args := updateBuilder.BuildOutput(query.Table, "", muts, query.WhereQuery, []string{"*"})
+	return m.queryOutput(ctx, col, statement, args)
+}
+
+// DeleteAnyOutput deletes records that match the query and scans the deleted rows into entities using OUTPUT clause.
+// Entities must be a pointer to slice, table of the entities is used when the query doesn't specify one.
+func (m MSSQL) DeleteAnyOutput(ctx context.Context, entities interface{}, query rel.Query) (int, error) {
+	deleteBuilder, ok := m.DeleteBuilder.(outputDeleteBuilder)
+	if !ok {
+		return 0, errors.New("mssql: delete builder doesn't support OUTPUT clause")
+	}
+
+	col := rel.NewCollection(entities)
+	if query.Table == "" {
+		query.Table = col.Table()
+	}
+
+	query = qualifyQuery(m.schema(ctx), query)
+	statement, args := deleteBuilder.BuildOutput(query.Table, query.WhereQuery, []string{"*"})
+	return m.queryOutput(ctx, col, statement, args)
+}
+
+// queryOutput executes statement and scans the outputted rows into collection.
+func (m MSSQL) queryOutput(ctx context.Context, col *rel.Collection, statement string, args []interface{}) (int, error) {
+	err := m.retryWrite(ctx, func() error {
+		rows, err := m.DoQuery(ctx, statement, args)
+		if err != nil {
+			return m.ErrorMapper(err)
+		}
+
+		defer rows.Close()
+		col.Reset()
+
+		fields, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(col.Add().Scanners(fields)...); err != nil {
+				return err
+			}
+		}
+
+		return m.ErrorMapper(rows.Err())
+	})
+
+	return col.Len(), err
 }
 
 // Insert inserts a record to database and returns its id.
+//
+// Returned id type follows the primary column type reported by the driver,
+// see scanPrimary for the details. Record that matches conflict keys of upsert keeps its primary value.
 func (m MSSQL) Insert(ctx context.Context, query rel.Query, primaryField string, mutates map[string]rel.Mutate, onConflict rel.OnConflict) (interface{}, error) {
+	var id interface{}
+
+	if insertBuilder, ok := m.InsertBuilder.(mssqlbuilder.Insert); ok {
+		if err := insertBuilder.OnConflict.Validate([]map[string]rel.Mutate{mutates}, onConflict); err != nil {
+			return nil, err
+		}
+	}
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	err := m.retryWrite(ctx, func() (err error) {
+		id, err = m.insert(ctx, query, primaryField, mutates, onConflict)
+		return err
+	})
+
+	return id, err
+}
+
+func (m MSSQL) insert(ctx context.Context, query rel.Query, primaryField string, mutates map[string]rel.Mutate, onConflict rel.OnConflict) (interface{}, error) {
 	var (
-		id              int64
 		statement, args = m.InsertBuilder.Build(query.Table, primaryField, mutates, onConflict)
 		rows, err       = m.DoQuery(ctx, statement, args)
 	)
 
-	defer rows.Close()
-	if err == nil && rows.Next() {
-		rows.Scan(&id)
+	if err != nil {
+		return nil, m.ErrorMapper(err)
 	}
 
-	// hack for go-mssqldb driver which doesn't return unique error correctly.
-	if primaryField != "" && id == 0 {
-		return id, rel.ConstraintError{
-			Type: rel.UniqueConstraint,
-			Err:  err,
-		}
+	ids, ordinals, err := scanPrimaries(rows)
+	if err != nil {
+		return nil, m.ErrorMapper(err)
 	}
-	return id, err
+
+	if ids = alignPrimaries(primaryField, []map[string]rel.Mutate{mutates}, ids, ordinals); len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	return nil, nil
 }
 
 // InsertAll inserts multiple records to database and returns its ids.
+//
+// Records that don't fit SQL Server limit of parameters or rows in a single statement are split into batches,
+// the batches are executed in order inside the current transaction, or a new one when there's none.
+// Ids are aligned to the records, record that matches conflict keys of upsert keeps its primary value.
+// Upsert fragment with arguments is rejected when the records need more than one batch.
 func (m MSSQL) InsertAll(ctx context.Context, query rel.Query, primaryField string, fields []string, bulkMutates []map[string]rel.Mutate, onConflict rel.OnConflict) ([]interface{}, error) {
 	var (
-		ids             []interface{}
+		ids     []interface{}
+		batches = splitBulkMutates(fields, bulkMutates, len(onConflict.FragmentArgs))
+	)
+
+	if insertAllBuilder, ok := m.InsertAllBuilder.(mssqlbuilder.InsertAll); ok {
+		if err := insertAllBuilder.OnConflict.Validate(bulkMutates, onConflict); err != nil {
+			return nil, err
+		}
+	}
+
+	// fragment refers to its arguments by placeholder position, which only matches the first batch.
+	if len(onConflict.FragmentArgs) > 0 && len(batches) > 1 {
+		return nil, errors.New("mssql: upsert fragment with arguments can't be split into batches")
+	}
+
+	query = qualifyQuery(m.schema(ctx), query)
+
+	if m.Tx != nil {
+		return m.insertBatches(ctx, query, primaryField, fields, batches, onConflict)
+	}
+
+	err := m.retryWrite(ctx, func() (err error) {
+		if len(batches) == 1 {
+			ids, err = m.insertAll(ctx, query, primaryField, fields, bulkMutates, onConflict)
+		} else {
+			ids, err = m.insertBatchesTx(ctx, query, primaryField, fields, batches, onConflict)
+		}
+
+		return err
+	})
+
+	return ids, err
+}
+
+func (m MSSQL) insertBatchesTx(ctx context.Context, query rel.Query, primaryField string, fields []string, batches [][]map[string]rel.Mutate, onConflict rel.OnConflict) ([]interface{}, error) {
+	adapter, err := m.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := adapter.(*MSSQL).insertBatches(ctx, query, primaryField, fields, batches, onConflict)
+	if err != nil {
+		adapter.Rollback(ctx)
+		return nil, err
+	}
+
+	return ids, adapter.Commit(ctx)
+}
+
+func (m MSSQL) insertBatches(ctx context.Context, query rel.Query, primaryField string, fields []string, batches [][]map[string]rel.Mutate, onConflict rel.OnConflict) ([]interface{}, error) {
+	var ids []interface{}
+
+	for _, batch := range batches {
+		batchIds, err := m.insertAll(ctx, query, primaryField, fields, batch, onConflict)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, batchIds...)
+	}
+
+	return ids, nil
+}
+
+func (m MSSQL) insertAll(ctx context.Context, query rel.Query, primaryField string, fields []string, bulkMutates []map[string]rel.Mutate, onConflict rel.OnConflict) ([]interface{}, error) {
+	var (
 		statement, args = m.InsertAllBuilder.Build(query.Table, primaryField, fields, bulkMutates, onConflict)
 		rows, err       = m.DoQuery(ctx, statement, args)
 	)
 
+	if err != nil {
+		return nil, m.ErrorMapper(err)
+	}
+
+	ids, ordinals, err := scanPrimaries(rows)
+	if err != nil {
+		return nil, m.ErrorMapper(err)
+	}
+
+	return alignPrimaries(primaryField, bulkMutates, ids, ordinals), nil
+}
+
+// SQL Server limits of a single statement.
+const (
+	maxParameters = 2100
+	maxInsertRows = 1000
+)
+
+// splitBulkMutates into batches that fit SQL Server limit of parameters and rows of a single statement.
+// Every set mutate takes a parameter, while absent field is written as DEFAULT.
+func splitBulkMutates(fields []string, bulkMutates []map[string]rel.Mutate, extraParameters int) [][]map[string]rel.Mutate {
+	var (
+		batches    [][]map[string]rel.Mutate
+		start      = 0
+		parameters = extraParameters
+	)
+
+	for i, mutates := range bulkMutates {
+		count := 0
+		for _, field := range fields {
+			if mut, ok := mutates[field]; ok && mut.Type == rel.ChangeSetOp {
+				count++
+			}
+		}
+
+		if i > start && (parameters+count >= maxParameters || i-start >= maxInsertRows) {
+			batches = append(batches, bulkMutates[start:i])
+			start = i
+			parameters = extraParameters
+		}
+
+		parameters += count
+	}
+
+	return append(batches, bulkMutates[start:])
+}
+
+// scanPrimaries drains every result set of the outputted primary values.
+// Error raised by any statement in the batch is only reported after its preceding rows are read,
+// so the rows must be fully consumed before the result can be trusted.
+//
+// MERGE outputs its action and the ordinal of source row along with the primary value,
+// in which case the primary values of both inserted and updated rows are returned along with their ordinals.
+func scanPrimaries(rows *db.Rows) ([]interface{}, []int, error) {
+	var (
+		ids      []interface{}
+		ordinals []int
+	)
+
 	defer rows.Close()
-	if err == nil {
+
+	for {
+		var (
+			typ     = primaryType(rows)
+			action  = "INSERT"
+			ordinal = -1
+			dest    []interface{}
+		)
+
+		if columns, err := rows.Columns(); err == nil && len(columns) == 3 && columns[0] == "__action" {
+			dest = []interface{}{&action, &ordinal}
+			if ordinals == nil {
+				ordinals = []int{}
+			}
+		}
+
 		for rows.Next() {
-			var id int64
-			rows.Scan(&id)
+			id, err := scanPrimary(rows, typ, dest...)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// both inserted and updated rows of MERGE output their primary value,
+			// rows matched by ignore upsert aren't outputted and keep their primary value.
 			ids = append(ids, id)
+			if dest != nil {
+				ordinals = append(ordinals, ordinal)
+			}
+		}
+
+		if !rows.NextResultSet() {
+			break
 		}
 	}
 
-	// hack for go-mssqldb driver which doesn't return unique error correctly.
-	if primaryField != "" && len(ids) == 0 {
-		return ids, rel.ConstraintError{
-			Type: rel.UniqueConstraint,
-			Err:  err,
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return ids, ordinals, rows.Close()
+}
+
+// alignPrimaries places the outputted primary values of MERGE at the ordinal of their records,
+// while record that isn't outputted keeps its primary value, or nil when it's not set.
+func alignPrimaries(primaryField string, bulkMutates []map[string]rel.Mutate, ids []interface{}, ordinals []int) []interface{} {
+	if ordinals == nil {
+		return ids
+	}
+
+	result := make([]interface{}, len(bulkMutates))
+	for i, mutates := range bulkMutates {
+		if mut, ok := mutates[primaryField]; ok && mut.Type == rel.ChangeSetOp {
+			result[i] = mut.Value
 		}
 	}
 
-	return ids, err
+	for i, ordinal := range ordinals {
+		if ordinal >= 0 && ordinal < len(result) {
+			result[ordinal] = ids[i]
+		}
+	}
+
+	return result
+}
+
+// primaryType returns database type name of the outputted primary column, which is the last column.
+// DECIMAL and NUMERIC identity without scale is reported as BIGINT, so it's scanned as integer.
+func primaryType(rows *db.Rows) string {
+	types, err := rows.ColumnTypes()
+	if err != nil || len(types) == 0 {
+		return ""
+	}
+
+	typ := types[len(types)-1].DatabaseTypeName()
+	if typ == "DECIMAL" || typ == "NUMERIC" {
+		if _, scale, ok := types[len(types)-1].DecimalSize(); ok && scale == 0 {
+			return "BIGINT"
+		}
+	}
+
+	return typ
+}
+
+// scanPrimary scans primary value of current row into go type matching its database type.
+// Integer types are returned as int64, character types as string and UNIQUEIDENTIFIER
+// as [16]byte based uniqueIdentifier, which is convertible to any [16]byte based uuid type.
+// Preceding columns of the row are scanned into dest.
+func scanPrimary(rows *db.Rows, typ string, dest ...interface{}) (interface{}, error) {
+	switch typ {
+	case "TINYINT", "SMALLINT", "INT", "BIGINT":
+		var id int64
+		err := rows.Scan(append(dest, &id)...)
+		return id, err
+	case "UNIQUEIDENTIFIER":
+		var id uniqueIdentifier
+		err := rows.Scan(append(dest, &id)...)
+		return id, err
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR":
+		var id string
+		err := rows.Scan(append(dest, &id)...)
+		return id, err
+	case "SQL_VARIANT":
+		// primary values outputted into table variable, driver returns UNIQUEIDENTIFIER variant as raw bytes.
+		var id interface{}
+		if err := rows.Scan(append(dest, &id)...); err != nil {
+			return nil, err
+		}
+
+		if b, ok := id.([]byte); ok && len(b) == 16 {
+			var uid uniqueIdentifier
+			err := uid.Scan(b)
+			return uid, err
+		}
+
+		return id, nil
+	default:
+		var id interface{}
+		err := rows.Scan(append(dest, &id)...)
+		return id, err
+	}
+}
+
+// uniqueIdentifier value of UNIQUEIDENTIFIER column in the byte order of its string representation.
+//
+// Driver isn't imported for its UniqueIdentifier type, so the adapter works with either
+// microsoft or denisenkom driver, which can't be imported together.
+type uniqueIdentifier [16]byte
+
+// Scan UNIQUEIDENTIFIER, whose first three groups are transmitted in little-endian byte order.
+func (u *uniqueIdentifier) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case []byte:
+		if len(v) != 16 {
+			return errors.New("mssql: invalid UNIQUEIDENTIFIER length: " + strconv.Itoa(len(v)))
+		}
+
+		copy(u[:], v)
+		u.swap()
+		return nil
+	case string:
+		b, err := hex.DecodeString(strings.ReplaceAll(v, "-", ""))
+		if err != nil || len(b) != 16 {
+			return errors.New("mssql: invalid UNIQUEIDENTIFIER: " + v)
+		}
+
+		copy(u[:], b)
+		return nil
+	}
+
+	return errors.New("mssql: can't scan UNIQUEIDENTIFIER")
+}
+
+// Value of UNIQUEIDENTIFIER in its transmitted byte order.
+func (u uniqueIdentifier) Value() (driver.Value, error) {
+	u.swap()
+	return u[:], nil
+}
+
+// String representation of UNIQUEIDENTIFIER, e.g: 01234567-89AB-CDEF-0123-456789ABCDEF.
+func (u uniqueIdentifier) String() string {
+	return strings.ToUpper(hex.EncodeToString(u[:4]) + "-" + hex.EncodeToString(u[4:6]) + "-" +
+		hex.EncodeToString(u[6:8]) + "-" + hex.EncodeToString(u[8:10]) + "-" + hex.EncodeToString(u[10:]))
+}
+
+// swap byte order of the first three groups.
+func (u *uniqueIdentifier) swap() {
+	u[0], u[1], u[2], u[3] = u[3], u[2], u[1], u[0]
+	u[4], u[5] = u[5], u[4]
+	u[6], u[7] = u[7], u[6]
+}
+
+// OutputInto configures tables whose inserted primary values are outputted into a table variable,
+// which is required by SQL Server when the table has enabled triggers.
+//
+//	adapter.OutputInto(mssqlbuilder.OutputIntoTables("orders", "invoices"))
+func (m *MSSQL) OutputInto(outputInto mssqlbuilder.OutputInto) {
+	if insertBuilder, ok := m.InsertBuilder.(mssqlbuilder.Insert); ok {
+		insertBuilder.OutputInto = outputInto
+		m.InsertBuilder = insertBuilder
+	}
+
+	if insertAllBuilder, ok := m.InsertAllBuilder.(mssqlbuilder.InsertAll); ok {
+		insertAllBuilder.OutputInto = outputInto
+		m.InsertAllBuilder = insertAllBuilder
+	}
 }
 
 // Name of database adapter.
@@ -88,29 +698,15 @@ func (MSSQL) Name() string {
 
 // New mssql adapter using existing connection.
 func New(db *db.DB) rel.Adapter {
-	var (
-		bufferFactory    = builder.BufferFactory{AllowTableSchema: true, ArgumentPlaceholder: "@p", ArgumentOrdinal: true, BoolTrueValue: "1", BoolFalseValue: "0", Quoter: builder.Quote{IDPrefix: "[", IDSuffix: "]", IDSuffixEscapeChar: "]", ValueQuote: "'", ValueQuoteEscapeChar: "'"}}
-		filterBuilder    = builder.Filter{}
-		queryBuilder     = mssqlbuilder.Query{Query: builder.Query{BufferFactory: bufferFactory, Filter: filterBuilder}}
-		InsertBuilder    = mssqlbuilder.Insert{BufferFactory: bufferFactory}
-		insertAllBuilder = mssqlbuilder.InsertAll{BufferFactory: bufferFactory}
-		updateBuilder    = builder.Update{BufferFactory: bufferFactory, Query: queryBuilder, Filter: filterBuilder}
-		deleteBuilder    = builder.Delete{BufferFactory: bufferFactory, Query: queryBuilder, Filter: filterBuilder}
-		ddlBufferFactory = builder.BufferFactory{InlineValues: true, BoolTrueValue: "1", BoolFalseValue: "0", Quoter: builder.Quote{IDPrefix: "[", IDSuffix: "]", IDSuffixEscapeChar: "]", ValueQuote: "'", ValueQuoteEscapeChar: "'"}}
-		ddlQueryBuilder  = builder.Query{BufferFactory: ddlBufferFactory, Filter: filterBuilder}
-		tableBuilder     = mssqlbuilder.Table{BufferFactory: ddlBufferFactory, ColumnMapper: columnMapper, DropKeyMapper: sql.DropKeyMapper}
-		indexBuilder     = mssqlbuilder.Index{BufferFactory: ddlBufferFactory, Query: ddlQueryBuilder, Filter: filterBuilder}
-	)
-
 	return &MSSQL{
 		SQL: sql.SQL{
-			QueryBuilder:     queryBuilder,
-			InsertBuilder:    InsertBuilder,
-			InsertAllBuilder: insertAllBuilder,
-			UpdateBuilder:    updateBuilder,
-			DeleteBuilder:    deleteBuilder,
-			TableBuilder:     tableBuilder,
-			IndexBuilder:     indexBuilder,
+			QueryBuilder:     NewQueryBuilder(),
+			InsertBuilder:    NewInsertBuilder(),
+			InsertAllBuilder: NewInsertAllBuilder(),
+			UpdateBuilder:    NewUpdateBuilder(),
+			DeleteBuilder:    NewDeleteBuilder(),
+			TableBuilder:     NewTableBuilder(),
+			IndexBuilder:     NewIndexBuilder(),
 			ErrorMapper:      errorMapper,
 			DB:               db,
 		},
@@ -134,40 +730,225 @@ func MustOpen(dsn string) rel.Adapter {
 	return adapter
 }
 
+// SQL Server error numbers mapped to constraint errors.
+const (
+	errUniqueConstraint      = 2627
+	errUniqueIndex           = 2601
+	errConstraintConflict    = 547
+	errNotNull               = 515
+	errStringTruncated       = 8152
+	errStringTruncatedColumn = 2628
+)
+
 func errorMapper(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	msg := err.Error()
+	for _, e := range sqlErrors(err) {
+		msg := e.SQLErrorMessage()
+
+		switch e.SQLErrorNumber() {
+		case errUniqueConstraint:
+			// Violation of %ls constraint '%.*ls'. Cannot insert duplicate key in object '%.*ls'.
+			return rel.ConstraintError{
+				Key:  extractQuoted(msg, '\'', 0),
+				Type: rel.UniqueConstraint,
+				Err:  err,
+			}
+		case errUniqueIndex:
+			// Cannot insert duplicate key row in object '%.*ls' with unique index '%.*ls'.
+			return rel.ConstraintError{
+				Key:  extractQuoted(msg, '\'', 1),
+				Type: rel.UniqueConstraint,
+				Err:  err,
+			}
+		case errConstraintConflict:
+			// The %ls statement conflicted with the %ls constraint "%.*ls".
+			// error number is shared by foreign key and check constraint, the constraint type is told apart by
+			// the FOREIGN KEY, REFERENCE or CHECK keyword substituted into the message, which isn't translated
+			// by localized messages, but still depends on the keyword preceding the constraint name.
+			typ := rel.ForeignKeyConstraint
+			if i := strings.IndexByte(msg, '"'); i >= 0 && hasKeyword(msg[:i], "CHECK") {
+				typ = rel.CheckConstraint
+			}
 
-	switch {
-	case strings.HasPrefix(msg, "mssql: Violation of PRIMARY KEY"):
-		return rel.ConstraintError{
-			Key:  sql.ExtractString(msg, "constraint '", "'"),
-			Type: rel.UniqueConstraint,
-			Err:  err,
+			return rel.ConstraintError{
+				Key:  extractQuoted(msg, '"', 0),
+				Type: typ,
+				Err:  err,
+			}
+		case errNotNull:
+			// Cannot insert the value NULL into column '%.*ls', table '%.*ls'; column does not allow nulls.
+			return rel.ConstraintError{
+				Key:  extractQuoted(msg, '\'', 0),
+				Type: rel.NotNullConstraint,
+				Err:  err,
+			}
+		case errStringTruncated, errStringTruncatedColumn:
+			// column length acts as check constraint, only the newer message describes the column.
+			// String or binary data would be truncated in table '%.*ls', column '%.*ls'. Truncated value: '%.*ls'.
+			key := ""
+			if e.SQLErrorNumber() == errStringTruncatedColumn {
+				key = extractQuoted(msg, '\'', 1)
+			}
+
+			return rel.ConstraintError{
+				Key:  key,
+				Type: rel.CheckConstraint,
+				Err:  err,
+			}
 		}
-	case strings.HasPrefix(msg, "mssql: Violation of UNIQUE KEY"):
-		return rel.ConstraintError{
-			Key:  sql.ExtractString(msg, "constraint '", "'"),
-			Type: rel.UniqueConstraint,
-			Err:  err,
+	}
+
+	return err
+}
+
+// sqlError is implemented by errors of both microsoft and denisenkom driver.
+type sqlError interface {
+	SQLErrorNumber() int32
+	SQLErrorMessage() string
+}
+
+// sqlErrors returns all errors reported by the server, empty when err isn't reported by the server.
+//
+// Error of both drivers describes the last error and lists every error in its All field,
+// which is read using reflection, since importing either driver conflicts with the other.
+func sqlErrors(err error) []sqlError {
+	var sqlErr sqlError
+	if !errors.As(err, &sqlErr) {
+		return nil
+	}
+
+	if v := reflect.Indirect(reflect.ValueOf(sqlErr)); v.Kind() == reflect.Struct {
+		if all := v.FieldByName("All"); all.Kind() == reflect.Slice && all.Len() > 0 {
+			errs := make([]sqlError, 0, all.Len())
+			for i := 0; i < all.Len(); i++ {
+				if e, ok := all.Index(i).Interface().(sqlError); ok {
+					errs = append(errs, e)
+				}
+			}
+
+			if len(errs) > 0 {
+				return errs
+			}
 		}
-	case strings.HasPrefix(msg, "mssql: The UPDATE statement conflicted with the FOREIGN KEY"):
-		return rel.ConstraintError{
-			Key:  sql.ExtractString(msg, "FOREIGN KEY constraint \"", "\""),
-			Type: rel.ForeignKeyConstraint,
-			Err:  err,
+	}
+
+	return []sqlError{sqlErr}
+}
+
+// hasKeyword reports whether msg contains keyword as a whole word.
+func hasKeyword(msg string, keyword string) bool {
+	words := strings.FieldsFunc(msg, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	for _, word := range words {
+		if word == keyword {
+			return true
 		}
-	case strings.HasPrefix(msg, "mssql: The UPDATE statement conflicted with the CHECK"):
-		return rel.ConstraintError{
-			Key:  sql.ExtractString(msg, "FOREIGN KEY constraint \"", "\""),
-			Type: rel.CheckConstraint,
-			Err:  err,
+	}
+
+	return false
+}
+
+// extractQuoted returns the nth string enclosed by quote character in the message.
+func extractQuoted(msg string, quote byte, nth int) string {
+	for {
+		start := strings.IndexByte(msg, quote)
+		if start < 0 {
+			return ""
 		}
-	default:
-		return err
+
+		end := strings.IndexByte(msg[start+1:], quote)
+		if end < 0 {
+			return ""
+		}
+
+		if nth == 0 {
+			return msg[start+1 : start+1+end]
+		}
+
+		msg = msg[start+end+2:]
+		nth--
+	}
+}
+
+// SQL Server column types that can be used as rel.ColumnType, size and precision are taken from rel.Column.
+const (
+	// TinyInt column type.
+	TinyInt rel.ColumnType = "TINYINT"
+	// Money column type.
+	Money rel.ColumnType = "MONEY"
+	// UniqueIdentifier column type.
+	UniqueIdentifier rel.ColumnType = "UNIQUEIDENTIFIER"
+	// DateTime2 column type, fractional seconds precision is taken from column precision.
+	DateTime2 rel.ColumnType = "DATETIME2"
+	// DateTimeOffset column type, fractional seconds precision is taken from column precision.
+	DateTimeOffset rel.ColumnType = "DATETIMEOFFSET"
+	// NChar column type, length is taken from column limit.
+	NChar rel.ColumnType = "NCHAR"
+	// VarChar non-Unicode column type, length is taken from column limit.
+	VarChar rel.ColumnType = "VARCHAR"
+	// VarBinary column type, length is taken from column limit, VARBINARY(MAX) is used when the limit is not set.
+	VarBinary rel.ColumnType = "VARBINARY"
+)
+
+// MapColumnTypes returns column mapper that maps rel column types to other column types before mapping them
+// to SQL Server types, e.g: MapColumnTypes(map[rel.ColumnType]rel.ColumnType{rel.DateTime: DateTime2}).
+func MapColumnTypes(types map[rel.ColumnType]rel.ColumnType) builder.ColumnMapper {
+	return func(column *rel.Column) (string, int, int) {
+		if typ, ok := types[column.Type]; ok {
+			column.Type = typ
+		}
+
+		return columnMapper(column)
+	}
+}
+
+// LongString decides how rel.String column with limit above 4000 characters is mapped, see MapLongString.
+type LongString int
+
+const (
+	// LongStringMax maps long string to NVARCHAR(MAX), which is the default.
+	LongStringMax LongString = iota
+	// LongStringVarChar maps long string to non-unicode VARCHAR up to 8000 characters, and VARCHAR(MAX) beyond.
+	LongStringVarChar
+	// LongStringError rejects long string when the migration is applied.
+	LongStringError
+)
+
+// MapLongString returns column mapper that maps rel.String column with limit above 4000 characters
+// using the given strategy before mapping it using the given column mapper, or the default when nil.
+func MapLongString(longString LongString, mapper builder.ColumnMapper) builder.ColumnMapper {
+	if mapper == nil {
+		mapper = columnMapper
+	}
+
+	return func(column *rel.Column) (string, int, int) {
+		if column.Type != rel.String || column.Limit <= 4000 {
+			return mapper(column)
+		}
+
+		switch longString {
+		case LongStringVarChar:
+			column.Type = VarChar
+		case LongStringError:
+			// keeps the limit, so it's rejected by table builder validation.
+			mapper(column)
+			return "NVARCHAR", column.Limit, 0
+		}
+
+		return mapper(column)
+	}
+}
+
+// ColumnMapper replaces column mapper of table builder, see MapColumnTypes.
+func (m *MSSQL) ColumnMapper(columnMapper builder.ColumnMapper) {
+	if tableBuilder, ok := m.TableBuilder.(mssqlbuilder.Table); ok {
+		tableBuilder.ColumnMapper = columnMapper
+		m.TableBuilder = tableBuilder
 	}
 }
 
@@ -186,6 +967,10 @@ func columnMapper(column *rel.Column) (string, int, int) {
 		typ = "BIGINT NOT NULL IDENTITY(1,1)"
 	case rel.Bool:
 		typ = "BIT"
+	case TinyInt:
+		typ = "TINYINT"
+	case rel.SmallInt:
+		typ = "SMALLINT"
 	case rel.Int:
 		typ = "INT"
 	case rel.BigInt:
@@ -197,23 +982,57 @@ func columnMapper(column *rel.Column) (string, int, int) {
 		typ = "DECIMAL"
 		m = column.Precision
 		n = column.Scale
+
+		// scale is only written along with precision, which defaults to 18.
+		if m == 0 && n > 0 {
+			m = 18
+		}
+	case Money:
+		typ = "MONEY"
+	case UniqueIdentifier:
+		typ = "UNIQUEIDENTIFIER"
 	case rel.String:
 		typ = "NVARCHAR"
 		m = column.Limit
 		if m == 0 {
 			m = 255
 		} else if m > 4000 {
-			m = 4000
+			typ = "NVARCHAR(MAX)"
+			m = 0
+		}
+	case NChar:
+		typ = "NCHAR"
+		m = column.Limit
+	case VarChar:
+		typ = "VARCHAR"
+		m = column.Limit
+		if m == 0 {
+			m = 255
+		} else if m > 8000 {
+			typ = "VARCHAR(MAX)"
+			m = 0
+		}
+	case VarBinary:
+		typ = "VARBINARY"
+		m = column.Limit
+		if m == 0 || m > 8000 {
+			typ = "VARBINARY(MAX)"
+			m = 0
 		}
 	case rel.Text, rel.JSON:
 		typ = "NVARCHAR(MAX)"
 	case rel.Date:
 		typ = "DATE"
 		timeLayout = "2006-01-02"
-	case rel.DateTime:
+	case rel.DateTime, DateTimeOffset:
 		typ = "DATETIMEOFFSET"
+		m = column.Precision
+	case DateTime2:
+		typ = "DATETIME2"
+		m = column.Precision
 	case rel.Time:
 		typ = "TIME"
+		m = column.Precision
 		timeLayout = "15:04:05"
 	default:
 		typ = string(column.Type)
@@ -3,12 +3,18 @@ package mssql
 import (
 	"context"
 	db "database/sql"
+	"database/sql/driver"
+	"io"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
+	mssqlbuilder "github.com/go-rel/mssql/builder"
 	"github.com/go-rel/rel"
+	"github.com/go-rel/rel/where"
 	"github.com/go-rel/sql/specs"
-	_ "github.com/microsoft/go-mssqldb"
+	mssql "github.com/microsoft/go-mssqldb"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +33,11 @@ func TestAdapter_specs(t *testing.T) {
 	assert.Nil(t, err)
 	defer adapter.Close()
 
+	// specs insert multiple users without slug into its unique index.
+	indexBuilder := adapter.(*MSSQL).IndexBuilder.(mssqlbuilder.Index)
+	indexBuilder.NullsDistinct = true
+	adapter.(*MSSQL).IndexBuilder = indexBuilder
+
 	repo := rel.New(adapter)
 
 	// Prepare tables
@@ -64,6 +75,10 @@ func TestAdapter_specs(t *testing.T) {
 	specs.InsertBelongsTo(t, repo)
 	specs.Inserts(t, repo)
 	specs.InsertAll(t, repo)
+	specs.InsertOnConflictIgnore(t, repo)
+	specs.InsertOnConflictReplace(t, repo)
+	specs.InsertAllOnConflictIgnore(t, repo)
+	specs.InsertAllOnConflictReplace(t, repo)
 	// specs.InsertAllPartialCustomPrimary(t, repo) - not supported
 
 	// Update Specs
@@ -89,10 +104,11 @@ func TestAdapter_specs(t *testing.T) {
 	specs.DeleteAny(t, repo)
 
 	// Constraint specs
-	// Unique and Foreign constraint is not fully supported.
-	// Because of driver bug, any error occurred when inserting is treated as unique constraint error.
-	// specs.UniqueConstraintOnUpdate(t, repo)
+	specs.UniqueConstraintOnInsert(t, repo)
+	specs.UniqueConstraintOnUpdate(t, repo)
+	specs.ForeignKeyConstraintOnInsert(t, repo)
 	specs.ForeignKeyConstraintOnUpdate(t, repo)
+	specs.CheckConstraintOnInsert(t, repo)
 	specs.CheckConstraintOnUpdate(t, repo)
 }
 
@@ -157,9 +173,7 @@ func TestAdapter_Exec_error(t *testing.T) {
 }
 
 func TestAdapter_TableBuilder(t *testing.T) {
-	adapter, err := Open(dsn())
-	assert.Nil(t, err)
-	defer adapter.Close()
+	adapter := New(nil)
 
 	tests := []struct {
 		result string
@@ -203,3 +217,979 @@ func TestAdapter_TableBuilder(t *testing.T) {
 		})
 	}
 }
+
+// driverError mimics error of denisenkom driver.
+type driverError struct {
+	number  int32
+	message string
+	All     []driverError
+}
+
+func (e driverError) Error() string           { return e.message }
+func (e driverError) SQLErrorNumber() int32   { return e.number }
+func (e driverError) SQLErrorMessage() string { return e.message }
+
+func TestErrorMapper(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		result error
+	}{
+		{
+			name:   "nil",
+			err:    nil,
+			result: nil,
+		},
+		{
+			name:   "unknown",
+			err:    assert.AnError,
+			result: assert.AnError,
+		},
+		{
+			name:   "primary key",
+			err:    mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint 'PK__users'. Cannot insert duplicate key in object 'dbo.users'. The duplicate key value is (1)."},
+			result: rel.ConstraintError{Key: "PK__users", Type: rel.UniqueConstraint},
+		},
+		{
+			name:   "unique key",
+			err:    mssql.Error{Number: 2627, Message: "Violation of UNIQUE KEY constraint 'UQ__extras__slug'. Cannot insert duplicate key in object 'dbo.extras'. The duplicate key value is (slug)."},
+			result: rel.ConstraintError{Key: "UQ__extras__slug", Type: rel.UniqueConstraint},
+		},
+		{
+			name:   "unique index",
+			err:    mssql.Error{Number: 2601, Message: "Cannot insert duplicate key row in object 'dbo.extras' with unique index 'extras_slug_unique'. The duplicate key value is (slug)."},
+			result: rel.ConstraintError{Key: "extras_slug_unique", Type: rel.UniqueConstraint},
+		},
+		{
+			name:   "foreign key on insert",
+			err:    mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "FK__extras__user_id". The conflict occurred in database "rel", table "dbo.users", column 'id'.`},
+			result: rel.ConstraintError{Key: "FK__extras__user_id", Type: rel.ForeignKeyConstraint},
+		},
+		{
+			name:   "foreign key on delete",
+			err:    mssql.Error{Number: 547, Message: `The DELETE statement conflicted with the REFERENCE constraint "FK__extras__user_id". The conflict occurred in database "rel", table "dbo.extras", column 'user_id'.`},
+			result: rel.ConstraintError{Key: "FK__extras__user_id", Type: rel.ForeignKeyConstraint},
+		},
+		{
+			name:   "check",
+			err:    mssql.Error{Number: 547, Message: `The UPDATE statement conflicted with the CHECK constraint "extras_score_check". The conflict occurred in database "rel", table "dbo.extras", column 'score'.`},
+			result: rel.ConstraintError{Key: "extras_score_check", Type: rel.CheckConstraint},
+		},
+		{
+			name:   "localized check",
+			err:    mssql.Error{Number: 547, Message: `Die UPDATE-Anweisung steht in Konflikt mit der CHECK-Einschränkung "extras_score_check". Der Konflikt trat in der rel-Datenbank, Tabelle "dbo.extras", column 'score' auf.`},
+			result: rel.ConstraintError{Key: "extras_score_check", Type: rel.CheckConstraint},
+		},
+		{
+			name:   "localized foreign key",
+			err:    mssql.Error{Number: 547, Message: `Die INSERT-Anweisung steht in Konflikt mit der FOREIGN KEY-Einschränkung "FK__extras__user_id". Der Konflikt trat in der rel-Datenbank, Tabelle "dbo.users", column 'id' auf.`},
+			result: rel.ConstraintError{Key: "FK__extras__user_id", Type: rel.ForeignKeyConstraint},
+		},
+		{
+			name:   "not null",
+			err:    mssql.Error{Number: 515, Message: "Cannot insert the value NULL into column 'name', table 'rel.dbo.users'; column does not allow nulls. INSERT fails."},
+			result: rel.ConstraintError{Key: "name", Type: rel.NotNullConstraint},
+		},
+		{
+			name:   "truncated",
+			err:    mssql.Error{Number: 8152, Message: "String or binary data would be truncated."},
+			result: rel.ConstraintError{Type: rel.CheckConstraint},
+		},
+		{
+			name:   "truncated with column",
+			err:    mssql.Error{Number: 2628, Message: "String or binary data would be truncated in table 'rel.dbo.users', column 'name'. Truncated value: 'abc'."},
+			result: rel.ConstraintError{Key: "name", Type: rel.CheckConstraint},
+		},
+		{
+			name: "multiple errors",
+			err: mssql.Error{Number: 3621, Message: "The statement has been terminated.", All: []mssql.Error{
+				{Number: 2627, Message: "Violation of UNIQUE KEY constraint 'UQ__extras__slug'. Cannot insert duplicate key in object 'dbo.extras'."},
+				{Number: 3621, Message: "The statement has been terminated."},
+			}},
+			result: rel.ConstraintError{Key: "UQ__extras__slug", Type: rel.UniqueConstraint},
+		},
+		{
+			name:   "other driver",
+			err:    driverError{number: 2601, message: "Cannot insert duplicate key row in object 'dbo.extras' with unique index 'extras_slug_unique'."},
+			result: rel.ConstraintError{Key: "extras_slug_unique", Type: rel.UniqueConstraint},
+		},
+		{
+			name: "other driver multiple errors",
+			err: driverError{number: 3621, message: "The statement has been terminated.", All: []driverError{
+				{number: 515, message: "Cannot insert the value NULL into column 'name', table 'rel.dbo.users'; column does not allow nulls. INSERT fails."},
+				{number: 3621, message: "The statement has been terminated."},
+			}},
+			result: rel.ConstraintError{Key: "name", Type: rel.NotNullConstraint},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := errorMapper(test.err)
+			if ce, ok := err.(rel.ConstraintError); ok {
+				assert.Equal(t, test.err, ce.Err)
+				ce.Err = nil
+				err = ce
+			}
+
+			assert.Equal(t, test.result, err)
+		})
+	}
+}
+
+func TestAdapter_QueryBuilder_lock(t *testing.T) {
+	adapter := New(nil)
+
+	tests := []struct {
+		result string
+		query  rel.Query
+	}{
+		{
+			result: `SELECT * FROM [users] WITH (UPDLOCK, ROWLOCK) WHERE [users].[id]=@p1;`,
+			query:  rel.From("users").Where(where.Eq("id", 1)).Lock(string(rel.ForUpdate())),
+		},
+		{
+			result: `SELECT TOP (1) * FROM [jobs] WITH (UPDLOCK, ROWLOCK, READPAST) ORDER BY [jobs].[id] ASC;`,
+			query:  rel.From("jobs").Limit(1).SortAsc("id").Lock("FOR UPDATE SKIP LOCKED"),
+		},
+		{
+			result: `SELECT * FROM [users] WITH (HOLDLOCK);`,
+			query:  rel.From("users").Lock("FOR SHARE"),
+		},
+		{
+			result: `SELECT * FROM [users] WITH (NOLOCK);`,
+			query:  rel.From("users").Lock("NOLOCK"),
+		},
+		{
+			result: `SELECT * FROM [users] WITH (TABLOCKX);`,
+			query:  rel.From("users").Lock("WITH (TABLOCKX)"),
+		},
+		{
+			result: `SELECT * FROM [users] AS [u] WITH (UPDLOCK, ROWLOCK) JOIN [addresses] ON [addresses].[user_id]=[u].[id];`,
+			query:  rel.From("users as u").JoinOn("addresses", "addresses.user_id", "u.id").Lock("for update"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			statement, _ := adapter.(*MSSQL).QueryBuilder.Build(test.query)
+			assert.Equal(t, test.result, statement)
+		})
+	}
+}
+
+func TestAdapter_OutputInto(t *testing.T) {
+	adapter := New(nil).(*MSSQL)
+	adapter.OutputInto(mssqlbuilder.OutputIntoTables("audits"))
+
+	t.Run("Insert", func(t *testing.T) {
+		statement, _ := adapter.InsertBuilder.Build("audits", "id", map[string]rel.Mutate{"name": rel.Set("name", "a")}, rel.OnConflict{})
+		assert.Equal(t, "DECLARE @output TABLE ([__ordinal] INT IDENTITY(1,1), [id] SQL_VARIANT); INSERT INTO [audits] ([name]) OUTPUT [INSERTED].[id] INTO @output ([id]) VALUES (@p1); SELECT [id] FROM @output ORDER BY [__ordinal];", statement)
+	})
+
+	t.Run("InsertAll", func(t *testing.T) {
+		statement, _ := adapter.InsertAllBuilder.Build("audits", "id", []string{"name"}, []map[string]rel.Mutate{{"name": rel.Set("name", "a")}, {"name": rel.Set("name", "b")}}, rel.OnConflict{})
+		assert.Equal(t, "DECLARE @output TABLE ([__ordinal] INT IDENTITY(1,1), [id] SQL_VARIANT); INSERT INTO [audits] ([name]) OUTPUT [INSERTED].[id] INTO @output ([id]) VALUES (@p1),(@p2); SELECT [id] FROM @output ORDER BY [__ordinal];", statement)
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		statement, _ := adapter.InsertBuilder.Build("tenant.audits", "id", map[string]rel.Mutate{"name": rel.Set("name", "a")}, rel.OnConflict{})
+		assert.Equal(t, "DECLARE @output TABLE ([__ordinal] INT IDENTITY(1,1), [id] SQL_VARIANT); INSERT INTO [tenant].[audits] ([name]) OUTPUT [INSERTED].[id] INTO @output ([id]) VALUES (@p1); SELECT [id] FROM @output ORDER BY [__ordinal];", statement)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		statement, _ := adapter.InsertBuilder.Build("users", "id", map[string]rel.Mutate{"name": rel.Set("name", "a")}, rel.OnConflict{})
+		assert.Equal(t, "INSERT INTO [users] ([name]) OUTPUT [INSERTED].[id] VALUES (@p1);", statement)
+	})
+}
+
+type BulkRecord struct {
+	ID   int
+	Name string
+}
+
+func TestAdapter_BulkInsert(t *testing.T) {
+	adapter := MustOpen(dsn())
+	defer adapter.Close()
+
+	repo := rel.New(adapter)
+	repo.MustExec(ctx, "IF OBJECT_ID('bulk_records', 'U') IS NOT NULL DROP TABLE bulk_records; CREATE TABLE bulk_records (id INT IDENTITY(1,1) PRIMARY KEY, name NVARCHAR(255));")
+	defer repo.MustExec(ctx, "DROP TABLE bulk_records;")
+
+	records := make([]BulkRecord, 1500)
+	for i := range records {
+		records[i].Name = "bulk"
+	}
+
+	count, err := adapter.(*MSSQL).BulkInsert(ctx, &records, BulkOptions{BatchSize: 500, Tablock: true})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(records)), count)
+	assert.Equal(t, len(records), repo.MustCount(ctx, "bulk_records"))
+
+	t.Run("transaction", func(t *testing.T) {
+		err := repo.Transaction(ctx, func(ctx context.Context) error {
+			_, err := repo.Adapter(ctx).(*MSSQL).BulkInsert(ctx, &records, BulkOptions{CheckConstraints: true, FireTriggers: true})
+			return err
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2*len(records), repo.MustCount(ctx, "bulk_records"))
+	})
+}
+
+func TestCopyIn(t *testing.T) {
+	options := BulkOptions{BatchSize: 100, KilobytesPerBatch: 64, CheckConstraints: true, FireTriggers: true, KeepNulls: true, Tablock: true, Order: []string{"id"}}
+	driverOptions := mssql.BulkOptions{RowsPerBatch: 100, KilobytesPerBatch: 64, CheckConstraints: true, FireTriggers: true, KeepNulls: true, Tablock: true, Order: []string{"id"}}
+
+	assert.Equal(t, mssql.CopyIn("[sales].[orders]", driverOptions, "id", "total"), copyIn("[sales].[orders]", options, []string{"id", "total"}))
+	assert.Equal(t, mssql.CopyIn("users", mssql.BulkOptions{}, "name"), copyIn("users", BulkOptions{}, []string{"name"}))
+}
+
+func TestSplitBulkMutates(t *testing.T) {
+	bulkMutates := func(n int, fields ...string) []map[string]rel.Mutate {
+		result := make([]map[string]rel.Mutate, n)
+		for i := range result {
+			result[i] = make(map[string]rel.Mutate, len(fields))
+			for _, field := range fields {
+				result[i][field] = rel.Set(field, i)
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		fields      []string
+		bulkMutates []map[string]rel.Mutate
+		extra       int
+		sizes       []int
+	}{
+		{
+			name:        "single batch",
+			fields:      []string{"name"},
+			bulkMutates: bulkMutates(10, "name"),
+			sizes:       []int{10},
+		},
+		{
+			name:        "rows limit",
+			fields:      []string{"name"},
+			bulkMutates: bulkMutates(2500, "name"),
+			sizes:       []int{1000, 1000, 500},
+		},
+		{
+			name:        "parameters limit",
+			fields:      []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+			bulkMutates: bulkMutates(300, "a", "b", "c", "d", "e", "f", "g", "h"),
+			sizes:       []int{262, 38},
+		},
+		{
+			name:        "parameters limit with fragment arguments",
+			fields:      []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+			bulkMutates: bulkMutates(300, "a", "b", "c", "d", "e", "f", "g", "h"),
+			extra:       3,
+			sizes:       []int{262, 38},
+		},
+		{
+			name:        "absent fields are written as default",
+			fields:      []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+			bulkMutates: bulkMutates(300, "a", "b", "c", "d"),
+			sizes:       []int{300},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				sizes   []int
+				total   int
+				batches = splitBulkMutates(test.fields, test.bulkMutates, test.extra)
+			)
+
+			for _, batch := range batches {
+				sizes = append(sizes, len(batch))
+				total += len(batch)
+			}
+
+			assert.Equal(t, test.sizes, sizes)
+			assert.Equal(t, len(test.bulkMutates), total)
+		})
+	}
+}
+
+func TestAlignPrimaries(t *testing.T) {
+	bulkMutates := []map[string]rel.Mutate{
+		{"email": rel.Set("email", "a@b.com")},
+		{"id": rel.Set("id", 5), "email": rel.Set("email", "b@b.com")},
+		{"email": rel.Set("email", "c@b.com")},
+		{"id": rel.Set("id", 7), "email": rel.Set("email", "d@b.com")},
+	}
+
+	tests := []struct {
+		name     string
+		result   []interface{}
+		ids      []interface{}
+		ordinals []int
+	}{
+		{
+			name:   "insert",
+			result: []interface{}{int64(1), int64(2)},
+			ids:    []interface{}{int64(1), int64(2)},
+		},
+		{
+			name:     "merge",
+			result:   []interface{}{int64(11), 5, nil, int64(12)},
+			ids:      []interface{}{int64(12), int64(11)},
+			ordinals: []int{3, 0},
+		},
+		{
+			name:     "merge with updated rows",
+			result:   []interface{}{int64(11), 5, int64(3), int64(12)},
+			ids:      []interface{}{int64(3), int64(12), int64(11)},
+			ordinals: []int{2, 3, 0},
+		},
+		{
+			name:     "merge without outputted rows",
+			result:   []interface{}{nil, 5, nil, 7},
+			ordinals: []int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.result, alignPrimaries("id", bulkMutates, test.ids, test.ordinals))
+		})
+	}
+}
+
+// outputColumn describes column of outputRows.
+type outputColumn struct {
+	name      string
+	typ       string
+	precision int64
+	scale     int64
+}
+
+// outputRows is a driver result set reporting database types the way go-mssqldb does.
+type outputRows struct {
+	columns []outputColumn
+	values  [][]driver.Value
+}
+
+func (r *outputRows) Columns() []string {
+	names := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		names[i] = column.name
+	}
+
+	return names
+}
+
+func (r *outputRows) Close() error { return nil }
+
+func (r *outputRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func (r *outputRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columns[index].typ
+}
+
+func (r *outputRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	column := r.columns[index]
+	return column.precision, column.scale, column.typ == "DECIMAL" || column.typ == "NUMERIC"
+}
+
+// outputConnector opens connections whose every query returns rows and every exec returns execErr.
+type outputConnector struct {
+	rows    *outputRows
+	execErr error
+}
+
+func (c outputConnector) Connect(context.Context) (driver.Conn, error) { return outputConn(c), nil }
+func (c outputConnector) Driver() driver.Driver                        { return nil }
+
+type outputConn outputConnector
+
+func (c outputConn) Prepare(string) (driver.Stmt, error) { return outputStmt(c), nil }
+func (c outputConn) Close() error                        { return nil }
+func (c outputConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type outputStmt outputConn
+
+func (s outputStmt) Close() error  { return nil }
+func (s outputStmt) NumInput() int { return -1 }
+func (s outputStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.ResultNoRows, s.execErr
+}
+func (s outputStmt) Query([]driver.Value) (driver.Rows, error) { return s.rows, nil }
+
+func TestScanPrimaries(t *testing.T) {
+	var (
+		uuid = []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+		uid  = uniqueIdentifier{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	)
+
+	tests := []struct {
+		name     string
+		rows     *outputRows
+		ids      []interface{}
+		ordinals []int
+	}{
+		{
+			name: "int",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "INT"}}, values: [][]driver.Value{{int64(1)}, {int64(2)}}},
+			ids:  []interface{}{int64(1), int64(2)},
+		},
+		{
+			name: "decimal without scale",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "DECIMAL", precision: 18}}, values: [][]driver.Value{{[]byte("12")}}},
+			ids:  []interface{}{int64(12)},
+		},
+		{
+			name: "numeric without scale",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "NUMERIC", precision: 10}}, values: [][]driver.Value{{[]byte("7")}}},
+			ids:  []interface{}{int64(7)},
+		},
+		{
+			name: "decimal with scale",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "DECIMAL", precision: 10, scale: 2}}, values: [][]driver.Value{{[]byte("1.50")}}},
+			ids:  []interface{}{[]byte("1.50")},
+		},
+		{
+			name: "uniqueidentifier",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "UNIQUEIDENTIFIER"}}, values: [][]driver.Value{{uuid}}},
+			ids:  []interface{}{uid},
+		},
+		{
+			name: "sql variant",
+			rows: &outputRows{columns: []outputColumn{{name: "id", typ: "SQL_VARIANT"}}, values: [][]driver.Value{{uuid}, {int64(3)}}},
+			ids:  []interface{}{uid, int64(3)},
+		},
+		{
+			name: "nvarchar",
+			rows: &outputRows{columns: []outputColumn{{name: "code", typ: "NVARCHAR"}}, values: [][]driver.Value{{"abc"}}},
+			ids:  []interface{}{"abc"},
+		},
+		{
+			name: "merge",
+			rows: &outputRows{
+				columns: []outputColumn{{name: "__action", typ: "NVARCHAR"}, {name: "__ordinal", typ: "INT"}, {name: "id", typ: "BIGINT"}},
+				values:  [][]driver.Value{{"UPDATE", int64(0), int64(5)}, {"INSERT", int64(1), int64(6)}},
+			},
+			ids:      []interface{}{int64(5), int64(6)},
+			ordinals: []int{0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := db.OpenDB(outputConnector{rows: test.rows})
+			defer conn.Close()
+
+			rows, err := conn.Query("OUTPUT")
+			assert.Nil(t, err)
+			defer rows.Close()
+
+			ids, ordinals, err := scanPrimaries(rows)
+			assert.Nil(t, err)
+			assert.Equal(t, test.ids, ids)
+			assert.Equal(t, test.ordinals, ordinals)
+		})
+	}
+}
+
+func TestUniqueIdentifier(t *testing.T) {
+	var (
+		raw = []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+		uid uniqueIdentifier
+	)
+
+	assert.Nil(t, uid.Scan(raw))
+	assert.Equal(t, "01234567-89AB-CDEF-0123-456789ABCDEF", uid.String())
+
+	value, err := uid.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, raw, value)
+
+	var parsed uniqueIdentifier
+	assert.Nil(t, parsed.Scan("01234567-89ab-cdef-0123-456789abcdef"))
+	assert.Equal(t, uid, parsed)
+
+	assert.EqualError(t, parsed.Scan(raw[:4]), "mssql: invalid UNIQUEIDENTIFIER length: 4")
+	assert.EqualError(t, parsed.Scan("0123"), "mssql: invalid UNIQUEIDENTIFIER: 0123")
+	assert.EqualError(t, parsed.Scan(1), "mssql: can't scan UNIQUEIDENTIFIER")
+}
+
+func TestAdapter_InsertAll_upsertFields(t *testing.T) {
+	adapter := New(nil)
+
+	_, err := adapter.InsertAll(context.TODO(), rel.From("users"), "id", []string{"email", "name"}, []map[string]rel.Mutate{
+		{"email": rel.Set("email", "a@b.com"), "name": rel.Set("name", "a")},
+		{"email": rel.Set("email", "b@b.com")},
+	}, rel.OnConflict{Keys: []string{"email"}, Ignore: true})
+
+	assert.EqualError(t, err, "mssql: field is not set by every record of upsert: name")
+
+	_, err = adapter.Insert(context.TODO(), rel.From("users"), "id", map[string]rel.Mutate{
+		"name": rel.Set("name", "a"),
+	}, rel.OnConflict{Keys: []string{"email"}, Ignore: true})
+
+	assert.EqualError(t, err, "mssql: conflict key is not set by every record: email")
+}
+
+func TestAdapter_InsertAll_fragmentArgsBatches(t *testing.T) {
+	var (
+		adapter     = New(nil)
+		bulkMutates = make([]map[string]rel.Mutate, 1100)
+	)
+
+	// 2200 parameters of the records exceed limit of a single statement.
+	for i := range bulkMutates {
+		bulkMutates[i] = map[string]rel.Mutate{
+			"email": rel.Set("email", "user"+strconv.Itoa(i)+"@b.com"),
+			"name":  rel.Set("name", "user"),
+		}
+	}
+
+	_, err := adapter.InsertAll(context.TODO(), rel.From("users"), "id", []string{"email", "name"}, bulkMutates,
+		rel.OnConflict{Keys: []string{"email"}, Fragment: "UPDATE SET [name] = @p2201", FragmentArgs: []interface{}{"updated"}})
+
+	assert.EqualError(t, err, "mssql: upsert fragment with arguments can't be split into batches")
+}
+
+func TestAdapter_Transaction_nested(t *testing.T) {
+	adapter := MustOpen(dsn())
+	defer adapter.Close()
+
+	repo := rel.New(adapter)
+	repo.MustExec(ctx, "IF OBJECT_ID('nested_records', 'U') IS NOT NULL DROP TABLE nested_records; CREATE TABLE nested_records (id INT IDENTITY(1,1) PRIMARY KEY, name NVARCHAR(255));")
+	defer repo.MustExec(ctx, "DROP TABLE nested_records;")
+
+	err := repo.Transaction(ctx, func(ctx context.Context) error {
+		repo.MustExec(ctx, "INSERT INTO nested_records (name) VALUES ('outer');")
+
+		// inner transaction is rolled back to its savepoint.
+		assert.Equal(t, assert.AnError, repo.Transaction(ctx, func(ctx context.Context) error {
+			repo.MustExec(ctx, "INSERT INTO nested_records (name) VALUES ('inner rollback');")
+			return assert.AnError
+		}))
+
+		return repo.Transaction(ctx, func(ctx context.Context) error {
+			repo.MustExec(ctx, "INSERT INTO nested_records (name) VALUES ('inner commit');")
+			return nil
+		})
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, repo.MustCount(ctx, "nested_records"))
+	assert.Equal(t, 0, repo.MustCount(ctx, "nested_records", where.Eq("name", "inner rollback")))
+}
+
+func TestAdapter_release(t *testing.T) {
+	tests := []struct {
+		name    string
+		execErr error
+		idle    int
+	}{
+		{
+			name: "reset",
+			idle: 1,
+		},
+		{
+			name:    "reset error",
+			execErr: assert.AnError,
+			idle:    0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := db.OpenDB(outputConnector{execErr: test.execErr})
+			defer pool.Close()
+
+			conn, err := pool.Conn(context.TODO())
+			assert.Nil(t, err)
+
+			MSSQL{conn: conn}.release()
+			assert.Equal(t, test.idle, pool.Stats().Idle)
+		})
+	}
+}
+
+func TestAdapter_Transaction_isolationLevel(t *testing.T) {
+	adapter := MustOpen(dsn()).(*MSSQL)
+	defer adapter.Close()
+
+	isolationLevel := func(adapter rel.Adapter) int {
+		var level int
+		assert.Nil(t, adapter.(*MSSQL).Tx.QueryRowContext(ctx, "SELECT transaction_isolation_level FROM sys.dm_exec_sessions WHERE session_id = @@SPID;").Scan(&level))
+		return level
+	}
+
+	t.Run("context", func(t *testing.T) {
+		tx, err := adapter.Begin(WithIsolationLevel(ctx, db.LevelSerializable))
+		assert.Nil(t, err)
+		assert.Equal(t, 4, isolationLevel(tx))
+		assert.Nil(t, tx.Commit(ctx))
+	})
+
+	t.Run("adapter", func(t *testing.T) {
+		adapter := *adapter
+		adapter.IsolationLevel = db.LevelRepeatableRead
+
+		tx, err := adapter.Begin(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, isolationLevel(tx))
+		assert.Nil(t, tx.Rollback(ctx))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		tx, err := adapter.Begin(WithIsolationLevel(ctx, db.LevelSerializable))
+		assert.Nil(t, err)
+
+		nested, err := tx.Begin(WithIsolationLevel(ctx, db.LevelReadUncommitted))
+		assert.Nil(t, err)
+		assert.Equal(t, 4, isolationLevel(nested))
+		assert.Nil(t, nested.Commit(ctx))
+		assert.Nil(t, tx.Commit(ctx))
+	})
+}
+
+func TestAdapter_retry(t *testing.T) {
+	var (
+		deadlock = mssql.Error{Number: 1205, Message: "Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim. Rerun the transaction."}
+		ops      []string
+		adapter  = MSSQL{RetryPolicy: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}
+	)
+
+	adapter.Instrumentation(func(ctx context.Context, op string, message string, args ...interface{}) func(err error) {
+		ops = append(ops, op)
+		return func(err error) {}
+	})
+
+	t.Run("succeed after retry", func(t *testing.T) {
+		ops = nil
+		attempts := 0
+		err := adapter.retryRead(ctx, func() error {
+			attempts++
+			if attempts < 3 {
+				return deadlock
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []string{"adapter-retry", "adapter-retry"}, ops)
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		attempts := 0
+		err := adapter.retryRead(ctx, func() error {
+			attempts++
+			return deadlock
+		})
+
+		assert.Equal(t, deadlock, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		attempts := 0
+		err := adapter.retryRead(ctx, func() error {
+			attempts++
+			return rel.ConstraintError{Type: rel.UniqueConstraint, Err: mssql.Error{Number: 2627}}
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("write after deadlock", func(t *testing.T) {
+		attempts := 0
+		err := adapter.retryWrite(ctx, func() error {
+			attempts++
+			if attempts < 2 {
+				return deadlock
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("write after transient error", func(t *testing.T) {
+		transient := mssql.Error{Number: 40197, Message: "The service has encountered an error processing your request. Please try again."}
+
+		attempts := 0
+		err := adapter.retryWrite(ctx, func() error {
+			attempts++
+			return transient
+		})
+
+		assert.Equal(t, transient, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("inside transaction", func(t *testing.T) {
+		adapter := adapter
+		adapter.Tx = &db.Tx{}
+
+		attempts := 0
+		err := adapter.retryWrite(ctx, func() error {
+			attempts++
+			return deadlock
+		})
+
+		assert.Equal(t, deadlock, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		attempts := 0
+		err := MSSQL{}.retryRead(ctx, func() error {
+			attempts++
+			return deadlock
+		})
+
+		assert.Equal(t, deadlock, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	assert.True(t, policy.Retryable(mssql.Error{Number: 40613}))
+	assert.True(t, policy.Retryable(rel.ConstraintError{Err: mssql.Error{Number: 3621, All: []mssql.Error{{Number: 1205}}}}))
+	assert.False(t, policy.Retryable(mssql.Error{Number: 2627}))
+	assert.False(t, policy.Retryable(assert.AnError))
+	assert.True(t, RetryPolicy{ErrorNumbers: []int32{1222}}.Retryable(mssql.Error{Number: 1222}))
+	assert.False(t, RetryPolicy{ErrorNumbers: []int32{1222}}.Retryable(mssql.Error{Number: 1205}))
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, policy.backoff(4))
+	assert.Equal(t, time.Second, policy.backoff(5))
+}
+
+type OutputRecord struct {
+	ID     int
+	Status string
+}
+
+func TestAdapter_Output(t *testing.T) {
+	adapter := MustOpen(dsn())
+	defer adapter.Close()
+
+	repo := rel.New(adapter)
+	repo.MustExec(ctx, "IF OBJECT_ID('output_records', 'U') IS NOT NULL DROP TABLE output_records; CREATE TABLE output_records (id INT IDENTITY(1,1) PRIMARY KEY, status NVARCHAR(255));")
+	defer repo.MustExec(ctx, "DROP TABLE output_records;")
+
+	repo.MustInsertAll(ctx, &[]OutputRecord{{Status: "pending"}, {Status: "pending"}, {Status: "done"}})
+
+	var updated []OutputRecord
+	count, err := adapter.(*MSSQL).UpdateAnyOutput(ctx, &updated, rel.Where(where.Eq("status", "pending")), rel.Set("status", "processing"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, updated, 2)
+	for _, record := range updated {
+		assert.NotZero(t, record.ID)
+		assert.Equal(t, "processing", record.Status)
+	}
+
+	var deleted []OutputRecord
+	count, err = adapter.(*MSSQL).DeleteAnyOutput(ctx, &deleted, rel.From("output_records").Where(where.Eq("status", "done")))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []OutputRecord{{ID: 3, Status: "done"}}, deleted)
+}
+
+func TestAdapter_schema(t *testing.T) {
+	adapter := New(nil).(*MSSQL).ForSchema("tenant1")
+
+	assert.Equal(t, "tenant1", adapter.schema(ctx))
+	assert.Equal(t, "tenant2", adapter.schema(WithSchema(ctx, "tenant2")))
+	assert.Equal(t, "", New(nil).(*MSSQL).schema(ctx))
+}
+
+func TestQualifyQuery(t *testing.T) {
+	adapter := New(nil).(*MSSQL)
+
+	tests := []struct {
+		result string
+		query  rel.Query
+	}{
+		{
+			result: `SELECT * FROM [tenant].[users] WHERE [tenant].[users].[id]=@p1;`,
+			query:  rel.From("users").Where(where.Eq("id", 1)),
+		},
+		{
+			result: `SELECT * FROM [tenant].[users] AS [u] JOIN [tenant].[addresses] ON [addresses].[user_id]=[u].[id];`,
+			query:  rel.From("users as u").JoinOn("addresses", "addresses.user_id", "u.id"),
+		},
+		{
+			result: `SELECT * FROM [tenant].[users] WHERE [tenant].[users].[id] IN (SELECT [tenant].[owners].[user_id] FROM [tenant].[owners]);`,
+			query:  rel.From("users").Where(where.In("id", rel.Select("user_id").From("owners"))),
+		},
+		{
+			result: `SELECT * FROM [tenant].[users] WHERE ([tenant].[users].[active]=@p1 OR [tenant].[users].[id] IN (SELECT [tenant].[admins].[user_id] FROM [tenant].[admins]));`,
+			query:  rel.From("users").Where(where.Or(where.Eq("active", true), where.In("id", rel.Select("user_id").From("admins")))),
+		},
+		{
+			result: `SELECT * FROM [shared].[countries];`,
+			query:  rel.From("shared.countries"),
+		},
+		{
+			result: `SELECT * FROM users;`,
+			query:  rel.Build("", rel.SQL("SELECT * FROM users;")),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			statement, _ := adapter.QueryBuilder.Build(qualifyQuery("tenant", test.query))
+			assert.Equal(t, test.result, statement)
+		})
+	}
+}
+
+func TestQualifyMigration(t *testing.T) {
+	adapter := New(nil).(*MSSQL)
+
+	table := rel.Table{
+		Op:   rel.SchemaCreate,
+		Name: "orders",
+		Definitions: []rel.TableDefinition{
+			rel.Column{Name: "customer_id", Type: rel.Int},
+			rel.Key{Type: rel.ForeignKey, Columns: []string{"customer_id"}, Reference: rel.ForeignKeyReference{Table: "customers", Columns: []string{"id"}}},
+			rel.Key{Type: rel.ForeignKey, Columns: []string{"country_id"}, Reference: rel.ForeignKeyReference{Table: "shared.countries", Columns: []string{"id"}}},
+		},
+	}
+
+	assert.Equal(t, "CREATE TABLE [tenant].[orders] ([customer_id] INT, FOREIGN KEY ([customer_id]) REFERENCES [tenant].[customers] ([id]), FOREIGN KEY ([country_id]) REFERENCES [shared].[countries] ([id]));",
+		adapter.TableBuilder.Build(qualifyMigration("tenant", table).(rel.Table)))
+	assert.Equal(t, "orders", table.Name)
+
+	index := rel.Index{Op: rel.SchemaCreate, Table: "orders", Name: "idx_customer_id", Columns: []string{"customer_id"}}
+	assert.Equal(t, "CREATE INDEX [idx_customer_id] ON [tenant].[orders] ([customer_id]);",
+		adapter.IndexBuilder.Build(qualifyMigration("tenant", index).(rel.Index)))
+
+	assert.Equal(t, rel.Raw("SELECT 1;"), qualifyMigration("tenant", rel.Raw("SELECT 1;")))
+}
+
+func TestColumnMapper(t *testing.T) {
+	tests := []struct {
+		result string
+		column rel.Column
+	}{
+		{result: "TINYINT", column: rel.Column{Type: TinyInt}},
+		{result: "SMALLINT", column: rel.Column{Type: rel.SmallInt}},
+		{result: "MONEY", column: rel.Column{Type: Money}},
+		{result: "DECIMAL(10,2)", column: rel.Column{Type: rel.Decimal, Precision: 10, Scale: 2}},
+		{result: "DECIMAL(18,4)", column: rel.Column{Type: rel.Decimal, Scale: 4}},
+		{result: "UNIQUEIDENTIFIER", column: rel.Column{Type: UniqueIdentifier}},
+		{result: "DATETIME2", column: rel.Column{Type: DateTime2}},
+		{result: "DATETIME2(3)", column: rel.Column{Type: DateTime2, Precision: 3}},
+		{result: "DATETIMEOFFSET(3)", column: rel.Column{Type: rel.DateTime, Precision: 3}},
+		{result: "DATETIMEOFFSET", column: rel.Column{Type: DateTimeOffset}},
+		{result: "TIME(3)", column: rel.Column{Type: rel.Time, Precision: 3}},
+		{result: "NVARCHAR(4000)", column: rel.Column{Type: rel.String, Limit: 4000}},
+		{result: "NVARCHAR(MAX)", column: rel.Column{Type: rel.String, Limit: 5000}},
+		{result: "NCHAR(2)", column: rel.Column{Type: NChar, Limit: 2}},
+		{result: "VARCHAR(255)", column: rel.Column{Type: VarChar}},
+		{result: "VARCHAR(8000)", column: rel.Column{Type: VarChar, Limit: 8000}},
+		{result: "VARCHAR(MAX)", column: rel.Column{Type: VarChar, Limit: 10000}},
+		{result: "VARBINARY(MAX)", column: rel.Column{Type: VarBinary}},
+		{result: "VARBINARY(64)", column: rel.Column{Type: VarBinary, Limit: 64}},
+		{result: "GEOGRAPHY", column: rel.Column{Type: "GEOGRAPHY"}},
+	}
+
+	tableBuilder := NewTableBuilder()
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			test.column.Name = "field"
+
+			buffer := tableBuilder.BufferFactory.Create()
+			tableBuilder.WriteColumn(&buffer, test.column)
+			assert.Equal(t, "[field] "+test.result, buffer.String())
+		})
+	}
+}
+
+func TestAdapter_ColumnMapper(t *testing.T) {
+	adapter := New(nil).(*MSSQL)
+	adapter.ColumnMapper(MapColumnTypes(map[rel.ColumnType]rel.ColumnType{rel.DateTime: DateTime2}))
+
+	assert.Equal(t, "CREATE TABLE [events] ([happened_at] DATETIME2(3) NOT NULL CONSTRAINT [DF_events_happened_at_610e7fbf] DEFAULT '2020-01-01 10:00:00', [date] DATE);", adapter.TableBuilder.Build(rel.Table{
+		Op:   rel.SchemaCreate,
+		Name: "events",
+		Definitions: []rel.TableDefinition{
+			rel.Column{Name: "happened_at", Type: rel.DateTime, Precision: 3, Required: true, Default: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)},
+			rel.Column{Name: "date", Type: rel.Date},
+		},
+	}))
+}
+
+func TestMapLongString(t *testing.T) {
+	tests := []struct {
+		result     string
+		longString LongString
+		column     rel.Column
+	}{
+		{result: "NVARCHAR(MAX)", longString: LongStringMax, column: rel.Column{Type: rel.String, Limit: 5000}},
+		{result: "NVARCHAR(100)", longString: LongStringVarChar, column: rel.Column{Type: rel.String, Limit: 100}},
+		{result: "VARCHAR(5000)", longString: LongStringVarChar, column: rel.Column{Type: rel.String, Limit: 5000}},
+		{result: "VARCHAR(MAX)", longString: LongStringVarChar, column: rel.Column{Type: rel.String, Limit: 10000}},
+		{result: "NVARCHAR(5000)", longString: LongStringError, column: rel.Column{Type: rel.String, Limit: 5000}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.result, func(t *testing.T) {
+			tableBuilder := NewTableBuilder()
+			tableBuilder.ColumnMapper = MapLongString(test.longString, nil)
+			test.column.Name = "field"
+
+			buffer := tableBuilder.BufferFactory.Create()
+			tableBuilder.WriteColumn(&buffer, test.column)
+			assert.Equal(t, "[field] "+test.result, buffer.String())
+		})
+	}
+}
+
+func TestAdapter_Apply_invalidColumn(t *testing.T) {
+	adapter := New(nil).(*MSSQL)
+	adapter.ColumnMapper(MapLongString(LongStringError, nil))
+
+	err := adapter.Apply(context.TODO(), rel.Table{
+		Op:   rel.SchemaCreate,
+		Name: "posts",
+		Definitions: []rel.TableDefinition{
+			rel.Column{Op: rel.SchemaCreate, Name: "body", Type: rel.String, Limit: 5000},
+		},
+	})
+
+	assert.EqualError(t, err, "mssql: invalid type of column body: NVARCHAR(5000)")
+}
+
+func TestAdapter_Apply_invalidIndexOptions(t *testing.T) {
+	adapter := New(nil)
+
+	err := adapter.Apply(context.TODO(), rel.Index{
+		Op:      rel.SchemaCreate,
+		Table:   "posts",
+		Name:    "idx_title",
+		Columns: []string{"title"},
+		Options: "FILLFACTOR=0",
+	})
+
+	assert.EqualError(t, err, "mssql: invalid index fill factor: 0")
+}
@@ -0,0 +1,139 @@
+package mssql
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-rel/rel"
+)
+
+// DefaultRetryErrorNumbers are deadlock victim and Azure SQL transient error numbers that are safe to retry.
+var DefaultRetryErrorNumbers = []int32{
+	1205,  // transaction was deadlocked and has been chosen as the deadlock victim.
+	4221,  // login to read-secondary failed due to long wait on HADR_DATABASE_WAIT_FOR_TRANSITION_TO_VERSIONING.
+	10928, // resource limit has been reached.
+	10929, // resource minimum guarantee can't be provided.
+	40143, // service has encountered an error processing your request.
+	40197, // service has encountered an error processing your request.
+	40501, // service is currently busy.
+	40613, // database is not currently available.
+	49918, // cannot process request, not enough resources.
+	49919, // cannot process create or update request, too many operations in progress.
+	49920, // cannot process request, too many operations in progress.
+}
+
+// RetryPolicy replays statements and transactions that failed because of deadlock or transient error.
+//
+// Statements are only replayed outside transaction, because SQL Server rolls back the whole transaction
+// of a deadlock victim, use Transaction to replay the whole transaction instead.
+// Queries are replayed after any retryable error, while writes are only replayed after deadlock,
+// since a write may already be committed when the connection fails because of transient error.
+// Raw statements executed using Exec are never replayed.
+type RetryPolicy struct {
+	// MaxAttempts including the first execution, retry is disabled when less than 2.
+	MaxAttempts int
+	// Backoff before the first retry, doubled on every subsequent retry.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// ErrorNumbers to retry, DefaultRetryErrorNumbers is used when empty.
+	ErrorNumbers []int32
+}
+
+// Retryable returns true when err is caused by one of retryable error numbers.
+func (rp RetryPolicy) Retryable(err error) bool {
+	errs := sqlErrors(err)
+	if len(errs) == 0 {
+		return false
+	}
+
+	numbers := rp.ErrorNumbers
+	if len(numbers) == 0 {
+		numbers = DefaultRetryErrorNumbers
+	}
+
+	for _, number := range numbers {
+		for _, e := range errs {
+			if e.SQLErrorNumber() == number {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (rp RetryPolicy) backoff(retry int) time.Duration {
+	delay := rp.Backoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+	}
+
+	if rp.MaxBackoff > 0 && (delay > rp.MaxBackoff || delay < 0) {
+		delay = rp.MaxBackoff
+	}
+
+	return delay
+}
+
+// errDeadlock is the error number of deadlock victim, whose statement is always rolled back.
+const errDeadlock = 1205
+
+// retry fn according to retry policy while the error is retryable,
+// every retry is reported to instrumenter as adapter-retry operation.
+func (m MSSQL) retry(ctx context.Context, fn func() error, retryable func(err error) bool) error {
+	err := fn()
+
+	for attempt := 1; err != nil && attempt < m.RetryPolicy.MaxAttempts && retryable(err); attempt++ {
+		finish := m.Instrumenter.Observe(ctx, "adapter-retry", "retry attempt "+strconv.Itoa(attempt)+" after: "+err.Error())
+
+		select {
+		case <-ctx.Done():
+			finish(ctx.Err())
+			return err
+		case <-time.After(m.RetryPolicy.backoff(attempt)):
+		}
+
+		err = fn()
+		finish(err)
+	}
+
+	return err
+}
+
+// retryRead retries fn according to retry policy only when the adapter is not a transaction.
+func (m MSSQL) retryRead(ctx context.Context, fn func() error) error {
+	if m.Tx != nil {
+		return fn()
+	}
+
+	return m.retry(ctx, fn, m.RetryPolicy.Retryable)
+}
+
+// retryWrite retries fn only when it's chosen as deadlock victim and the adapter is not a transaction.
+func (m MSSQL) retryWrite(ctx context.Context, fn func() error) error {
+	if m.Tx != nil {
+		return fn()
+	}
+
+	return m.retry(ctx, fn, func(err error) bool {
+		return m.RetryPolicy.Retryable(err) && RetryPolicy{ErrorNumbers: []int32{errDeadlock}}.Retryable(err)
+	})
+}
+
+// Transaction runs fn inside repo.Transaction and replays the whole transaction when it fails
+// because of deadlock or transient error, according to retry policy of the repository adapter.
+//
+// fn may be executed multiple times and must not have side effects outside the transaction.
+// Nested call inside a transaction is executed once, leaving the retry to the outermost transaction.
+func Transaction(ctx context.Context, repo rel.Repository, fn func(ctx context.Context) error) error {
+	adapter, ok := repo.Adapter(ctx).(*MSSQL)
+	if !ok || adapter.Tx != nil {
+		return repo.Transaction(ctx, fn)
+	}
+
+	return adapter.retry(ctx, func() error {
+		return repo.Transaction(ctx, fn)
+	}, adapter.RetryPolicy.Retryable)
+}
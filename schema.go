@@ -0,0 +1,150 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-rel/rel"
+)
+
+// CreateSchema migration step, creation is skipped when optional and the schema already exists.
+//
+//	mssql.CreateSchema(schema, "sales", true)
+//	schema.CreateTable("sales.orders", func(t *rel.Table) { ... })
+func CreateSchema(schema *rel.Schema, name string, optional bool) {
+	schema.Exec(rel.Raw(NewSchemaBuilder().Build(rel.SchemaCreate, name, optional)))
+}
+
+// DropSchema migration step, deletion is skipped when optional and the schema doesn't exist.
+func DropSchema(schema *rel.Schema, name string, optional bool) {
+	schema.Exec(rel.Raw(NewSchemaBuilder().Build(rel.SchemaDrop, name, optional)))
+}
+
+type schemaKey struct{}
+
+// WithSchema returns context that qualifies every unqualified table of queries, mutations and migrations
+// using the given schema, overriding MSSQL.Schema, e.g: to serve a tenant stored in its own schema.
+func WithSchema(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// ForSchema returns a copy of the adapter that qualifies every unqualified table using the given schema.
+// The copy shares the connection pool of the adapter.
+func (m MSSQL) ForSchema(schema string) *MSSQL {
+	m.Schema = schema
+	return &m
+}
+
+// schema used to qualify tables in the given context.
+func (m MSSQL) schema(ctx context.Context) string {
+	if schema, ok := ctx.Value(schemaKey{}).(string); ok {
+		return schema
+	}
+
+	return m.Schema
+}
+
+// qualifyTable prefixes table with schema, unless it's already qualified.
+// Table might be followed by its alias, e.g: "users as u".
+func qualifyTable(schema string, table string) string {
+	name := table
+	if i := strings.Index(strings.ToLower(table), " as "); i >= 0 {
+		name = table[:i]
+	}
+
+	if schema == "" || name == "" || strings.Contains(name, ".") {
+		return table
+	}
+
+	return schema + "." + table
+}
+
+// qualifyQuery qualifies the table, joined tables and tables of sub queries.
+// Fields qualified by table name remain valid, because schema is not part of the exposed name of a table.
+func qualifyQuery(schema string, query rel.Query) rel.Query {
+	if schema == "" || query.SQLQuery.Statement != "" {
+		return query
+	}
+
+	query.Table = qualifyTable(schema, query.Table)
+
+	if len(query.JoinQuery) > 0 {
+		joins := make([]rel.JoinQuery, len(query.JoinQuery))
+		for i, join := range query.JoinQuery {
+			join.Table = qualifyTable(schema, join.Table)
+			join.Filter = qualifyFilter(schema, join.Filter)
+			joins[i] = join
+		}
+
+		query.JoinQuery = joins
+	}
+
+	query.WhereQuery = qualifyFilter(schema, query.WhereQuery)
+	query.GroupQuery.Filter = qualifyFilter(schema, query.GroupQuery.Filter)
+
+	return query
+}
+
+func qualifyFilter(schema string, filter rel.FilterQuery) rel.FilterQuery {
+	filter.Value = qualifyValue(schema, filter.Value)
+
+	if len(filter.Inner) > 0 {
+		inner := make([]rel.FilterQuery, len(filter.Inner))
+		for i := range filter.Inner {
+			inner[i] = qualifyFilter(schema, filter.Inner[i])
+		}
+
+		filter.Inner = inner
+	}
+
+	return filter
+}
+
+func qualifyValue(schema string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case rel.Query:
+		return qualifyQuery(schema, v)
+	case rel.SubQuery:
+		v.Query = qualifyQuery(schema, v.Query)
+		return v
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = qualifyValue(schema, v[i])
+		}
+
+		return values
+	}
+
+	return value
+}
+
+// qualifyMigration qualifies table of the migration and tables referenced by its foreign keys.
+func qualifyMigration(schema string, migration rel.Migration) rel.Migration {
+	if schema == "" {
+		return migration
+	}
+
+	switch v := migration.(type) {
+	case rel.Table:
+		v.Name = qualifyTable(schema, v.Name)
+
+		definitions := make([]rel.TableDefinition, len(v.Definitions))
+		for i, def := range v.Definitions {
+			if key, ok := def.(rel.Key); ok && key.Type == rel.ForeignKey {
+				key.Reference.Table = qualifyTable(schema, key.Reference.Table)
+				def = key
+			}
+
+			definitions[i] = def
+		}
+
+		v.Definitions = definitions
+		return v
+	case rel.Index:
+		v.Table = qualifyTable(schema, v.Table)
+		return v
+	}
+
+	return migration
+}